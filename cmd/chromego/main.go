@@ -1,9 +1,11 @@
 package main
 
 import (
+	"os"
+
 	"github.com/Virace/chrome-go/internal"
 )
 
 func main() {
-	internal.Run()
+	os.Exit(internal.Run(os.Args[1:]))
 }
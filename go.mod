@@ -2,7 +2,10 @@ module github.com/Virace/chrome-go
 
 go 1.21
 
-require github.com/bodgit/sevenzip v1.6.0
+require (
+	github.com/bodgit/sevenzip v1.6.0
+	github.com/ulikunitz/xz v0.5.12
+)
 
 require (
 	github.com/andybalholm/brotli v1.1.1 // indirect
@@ -13,7 +16,6 @@ require (
 	github.com/hashicorp/golang-lru/v2 v2.0.7 // indirect
 	github.com/klauspost/compress v1.17.9 // indirect
 	github.com/pierrec/lz4/v4 v4.1.21 // indirect
-	github.com/ulikunitz/xz v0.5.12 // indirect
 	go4.org v0.0.0-20200411211856-f5505b9728dd // indirect
 	golang.org/x/text v0.20.0 // indirect
 )
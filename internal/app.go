@@ -5,161 +5,410 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
-	"sort"
 	"strings"
+	"time"
 )
 
-// Run 主应用入口
-func Run() {
+// session 单次运行的上下文
+type session struct {
+	cfg  *Config
+	opts *Options
+	ui   UI
+
+	plan      *UpdatePlan // 本次计算的更新计划，用于上报状态
+	lastError string      // 最近一次失败的原因，用于上报状态
+}
+
+// confirm 询问用户；静默模式下不弹窗，直接返回配置的应答
+func (s *session) confirm(title, message string, silentAnswer bool) bool {
+	if s.opts.Silent {
+		answer := "取消"
+		if silentAnswer {
+			answer = "确认"
+		}
+		logf("%s: 自动%s", title, answer)
+		return silentAnswer
+	}
+	return s.ui.ShowConfirm(title, message)
+}
+
+// info 显示提示信息；静默模式下写入日志，命令行调用时输出到标准输出
+func (s *session) info(title, message string) {
+	switch {
+	case s.opts.Silent:
+		logf("%s: %s", title, strings.ReplaceAll(message, "\n", "; "))
+	case s.opts.Console():
+		fmt.Println(message)
+	default:
+		s.ui.ShowInfo(title, message)
+	}
+}
+
+// say 输出进度消息；静默模式下写入日志，命令行调用时输出到标准输出，弹窗模式下不显示
+func (s *session) say(format string, args ...interface{}) {
+	switch {
+	case s.opts.Silent:
+		logf(format, args...)
+	case s.opts.Console():
+		fmt.Printf(format+"\n", args...)
+	}
+}
+
+// warn 输出警告；静默模式下写入日志，命令行调用时输出到标准错误，弹窗模式下不显示
+func (s *session) warn(message string) {
+	switch {
+	case s.opts.Silent:
+		logf("警告: %s", message)
+	case s.opts.Console():
+		fmt.Fprintln(os.Stderr, "警告: "+message)
+	}
+}
+
+// fail 显示错误信息并返回失败退出码；静默模式下写入日志，命令行调用时输出到标准错误
+func (s *session) fail(message string) int {
+	s.lastError = message
+	switch {
+	case s.opts.Console():
+		fmt.Fprintln(os.Stderr, "错误: "+message)
+	case s.opts.Silent:
+		logf("错误: %s", message)
+	default:
+		s.ui.ShowError(message)
+	}
+	return ExitFailed
+}
+
+// Run 主应用入口，使用当前平台的默认界面，返回进程退出码
+func Run(args []string) int {
+	return RunWithUI(args, NewDefaultUI())
+}
+
+// RunWithUI 使用指定界面运行，返回进程退出码
+func RunWithUI(args []string, ui UI) (code int) {
 	// 默认隐藏控制台窗口
 	HideConsole()
 
+	opts, err := ParseOptions(args)
+	if err != nil {
+		ui.ShowError("参数错误: " + err.Error())
+		return ExitFailed
+	}
+
+	// 命令行调用时输出到父进程控制台
+	if opts.Console() {
+		AttachParentConsole()
+	}
+
 	// 加载配置
 	cfg, err := LoadConfig()
 	if err != nil {
-		ShowError("加载配置失败: " + err.Error())
-		return
+		s := &session{cfg: DefaultConfig(), opts: opts, ui: ui}
+		if opts.Silent && !opts.DryRun {
+			if logFile, logErr := redirectOutputToFile(s.cfg.GetLogFile()); logErr == nil {
+				defer logFile.Close()
+			}
+		}
+		return s.fail("加载配置失败: " + err.Error())
+	}
+	if cfg.Silent {
+		opts.Silent = true
+	}
+	s := &session{cfg: cfg, opts: opts, ui: ui}
+
+	// 静默模式输出写入日志文件（团队策略强制静默模式时稍后重定向），在上报状态之后关闭
+	var logFile *os.File
+	defer func() {
+		if logFile != nil {
+			logFile.Close()
+		}
+	}()
+	startSilentLog := func(message string) {
+		if opts.DryRun {
+			return
+		}
+		if f, err := redirectOutputToFile(cfg.GetLogFile()); err == nil {
+			logFile = f
+		}
+		logf("ChromeGo %s %s", VersionString(), message)
+	}
+	if opts.Silent {
+		startSilentLog("静默模式启动")
+	}
+
+	// 加载主机认证信息
+	if err := ConfigureHTTP(cfg); err != nil {
+		return s.fail("加载凭据文件失败: " + err.Error())
+	}
+
+	if _, err := ParsePublicKeys(cfg.TrustedKeys); err != nil {
+		return s.fail("配置错误: " + err.Error())
+	}
+
+	// 签名元数据
+	if opts.Command == CommandSign {
+		return s.sign()
+	}
+
+	// 检查或更新结束后上报状态（预演模式不上报）
+	if opts.Command != CommandServe && opts.Command != CommandMirror && !opts.DryRun {
+		defer func() { s.report(code) }()
+	}
+
+	// 离线安装，不联网检查更新
+	if opts.Command == CommandInstall {
+		return s.installFromFile(opts.From, opts.SHA256)
+	}
+
+	// 局域网缓存服务
+	if opts.Command == CommandServe {
+		return s.serve(opts.Listen)
+	}
+
+	// 生成离线镜像，未指定通道时使用配置中的通道
+	if opts.Command == CommandMirror {
+		if len(opts.Mirror.Channels) == 0 {
+			opts.Mirror.Channels = []string{chromeChannelName(cfg.Channel)}
+		}
+		if opts.Key != "" {
+			key, err := LoadPrivateKey(opts.Key)
+			if err != nil {
+				return s.fail("读取私钥失败: " + err.Error())
+			}
+			opts.Mirror.SignKey = key
+		}
+		client := NewMetadataClient(cfg)
+		client.MinInterval = 0
+		defer client.Cache.Save()
+		if err := BuildOfflineMirror(client, opts.Mirror, cfg.GetThreads(), s.say); err != nil {
+			return s.fail("生成离线镜像失败: " + err.Error())
+		}
+		return ExitUpToDate
 	}
 
 	// 获取基础路径
-	exe, _ := os.Executable()
-	baseDir := filepath.Dir(exe)
+	baseDir := programDir()
 
 	// 检查 Chrome 和 Chrome++ 是否已安装
 	chromePath := cfg.GetChromePath()
-	chromePlusDllPath := cfg.GetChromePlusDllPath()
 	chromePlusIniPath := cfg.GetChromePlusIniPath()
+	state := ReadInstallState(cfg)
+	interactive := !opts.Silent && !opts.Console()
+	manual := opts.Command == CommandUpdate
 
-	chromeExists := fileExists(chromePath)
-	chromePlusExists := fileExists(chromePlusDllPath)
-
-	// 启动 Chrome（如果存在）
-	if chromeExists {
+	// 启动 Chrome（如果存在，静默模式和命令行调用不启动）
+	if state.ChromeExists && interactive {
 		startChrome(chromePath)
 	}
 
+	// 元数据客户端：check 命令忽略缓存，update 命令不受检查间隔限制（预演模式不写缓存）
+	client := NewMetadataClient(cfg)
+	switch opts.Command {
+	case CommandCheck:
+		client.Bypass = true
+	case CommandUpdate:
+		client.MinInterval = 0
+	}
+	if !opts.DryRun {
+		defer client.Cache.Save()
+	}
+
+	// 团队策略：覆盖强制的配置项，约束可更新的版本
+	team, err := s.loadTeamPolicy(client)
+	if err != nil {
+		if state.ChromeExists && interactive {
+			return ExitFailed
+		}
+		return s.fail(err.Error())
+	}
+
+	// 团队策略可能强制了静默模式和检查间隔，按覆盖后的配置重新设置
+	if cfg.Silent && !opts.Silent {
+		opts.Silent = true
+		interactive = false
+		startSilentLog("按团队策略以静默模式运行")
+	}
+	if opts.Command != CommandUpdate {
+		client.MinInterval = cfg.GetCheckInterval()
+	}
+
 	// 后台检测更新
-	latestVersion, err := GetLatestVersion(cfg.Channel)
+	latestVersion, err := client.GetLatestVersion(cfg.Channel)
 	if err != nil {
-		if !chromeExists {
+		if !state.ChromeExists || !interactive {
 			// Chrome 不存在且无法获取版本，显示错误
-			ShowError("无法获取更新信息: " + err.Error())
+			return s.fail("无法获取更新信息: " + err.Error())
 		}
-		return
+		return ExitFailed
+	}
+	if latestVersion.ChromePlusError != "" {
+		// Chrome++ 信息不可用时仍继续检查 Chrome 更新
+		s.warn(latestVersion.ChromePlusError)
 	}
 
-	// 判断 Chrome 是否需要更新
-	needChromeUpdate := false
-	if !chromeExists {
-		needChromeUpdate = true
-	} else if cfg.Version == "" {
-		needChromeUpdate = true
-	} else if CompareVersion(cfg.Version, latestVersion.ChromeVersion) {
-		// 检查是否已跳过此版本
-		if cfg.SkippedChromeVersion != latestVersion.ChromeVersion {
-			needChromeUpdate = true
-		}
+	// 防回滚：数据源版本低于曾见过的最高版本时警告或拒绝更新，元数据过旧时警告
+	now := time.Now()
+	rollback := CheckRollback(cfg, latestVersion, now)
+	if rollback.Refuse(cfg) {
+		return s.fail("拒绝更新: " + strings.Join(rollback.Rollbacks, "; ") + "\n数据源可能被篡改或已过期")
+	}
+	for _, message := range rollback.Rollbacks {
+		s.warn(message + "，数据源可能被篡改或已过期")
+	}
+	if rollback.Stale != "" {
+		s.warn(rollback.Stale)
 	}
 
-	// 判断 Chrome++ 是否需要更新
-	needChromePlusUpdate := false
-	if !chromePlusExists {
-		needChromePlusUpdate = true
-	} else if cfg.ChromePlusVersion == "" {
-		needChromePlusUpdate = true
-	} else if cfg.ChromePlusVersion != latestVersion.ChromePlusVersion {
-		// 检查是否已跳过此版本
-		if cfg.SkippedChromePlusVersion != latestVersion.ChromePlusVersion {
-			needChromePlusUpdate = true
-		}
+	// 记录首次发现新版本的时间，用于判断更新是否紧急及新版本发布时长（预演模式不保存）
+	seenChanged := RecordUpdateSeen(cfg, state, latestVersion, now)
+	seenChanged = RecordVersionSeen(cfg, latestVersion, now) || seenChanged
+	seenChanged = RecordHighestSeen(cfg, latestVersion) || seenChanged
+	if seenChanged && !opts.DryRun {
+		cfg.Save()
 	}
 
-	// 都不需要更新，静默退出
-	if !needChromeUpdate && !needChromePlusUpdate {
-		return
+	// 计算更新计划（静默模式和手动更新忽略已跳过的版本，手动更新忽略最短发布时长）
+	planOpts := PlanOptions{IgnoreSkipped: opts.Silent || manual, IgnoreSoak: manual, Now: now, Team: team}
+	if cfg.Policy.GetMinReleaseAge() > 0 && !manual {
+		source := &VersionHistorySource{BaseURL: cfg.VersionHistoryURL, Client: client}
+		planOpts.ChromeReleasedAt = chromeReleaseTime(cfg, source, latestVersion)
 	}
+	plan := BuildUpdatePlan(cfg, state, latestVersion, planOpts)
+	s.plan = plan
 
-	// 构建提示消息（所有更新都提示手动关闭浏览器）
-	var message string
-	if !chromeExists {
-		message = fmt.Sprintf("未检测到 Chrome，是否下载安装？\n\n"+
-			"Chrome 版本: %s\n"+
-			"Chrome++ 版本: %s\n\n"+
-			"请确保已关闭所有 Chrome 窗口后点击\"是\"开始安装",
-			latestVersion.ChromeVersion, latestVersion.ChromePlusVersion)
-	} else {
-		var updates []string
-		if needChromeUpdate {
-			updates = append(updates, fmt.Sprintf("Chrome: %s → %s", cfg.Version, latestVersion.ChromeVersion))
-		}
-		if needChromePlusUpdate {
-			oldVer := cfg.ChromePlusVersion
-			if oldVer == "" {
-				oldVer = "未安装"
+	// 首次发现的新版本发送通知（预演模式不发送）
+	if !opts.DryRun && s.notifyAvailable(plan) {
+		cfg.Save()
+	}
+
+	// 预演模式和 check 命令只输出计划
+	if opts.DryRun || opts.Command == CommandCheck {
+		if opts.JSON {
+			if err := plan.WriteJSON(os.Stdout); err != nil {
+				return s.fail("输出更新计划失败: " + err.Error())
 			}
-			updates = append(updates, fmt.Sprintf("Chrome++: %s → %s", oldVer, latestVersion.ChromePlusVersion))
+		} else {
+			plan.WriteText(os.Stdout)
 		}
+		return ExitUpToDate
+	}
 
-		message = "发现以下更新:\n\n"
-		for _, u := range updates {
-			message += "• " + u + "\n"
+	chromeChange := plan.Change(ComponentChrome)
+	plusChange := plan.Change(ComponentChromePlus)
+
+	// 都不需要更新，静默退出
+	if plan.Empty() {
+		if opts.Silent || manual {
+			s.info("检查更新", fmt.Sprintf("已是最新版本: Chrome %s, Chrome++ %s", cfg.Version, cfg.ChromePlusVersion))
 		}
-		message += "\n请手动关闭浏览器后点击\"是\"开始更新\n点击\"否\"将跳过此版本"
+		return ExitUpToDate
 	}
 
-	if !ShowConfirm("ChromeGo 更新", message) {
-		// 用户选择跳过，记录跳过的版本
-		configChanged := false
-		if needChromeUpdate && chromeExists {
-			cfg.SkippedChromeVersion = latestVersion.ChromeVersion
-			configChanged = true
+	// 维护窗口外不安装更新（首次安装和 --force 除外，静默模式下紧急更新除外）
+	if state.ChromeExists && !opts.Force && !InMaintenanceWindow(cfg.MaintenanceWindows, now) {
+		switch {
+		case opts.Silent && plan.Urgent():
+			logf("紧急更新，忽略维护窗口")
+		case opts.Silent || manual:
+			// 预先下载，等待维护窗口内安装
+			s.say("当前不在维护窗口内，仅下载更新")
+			share := s.sharePeers()
+			defer share.Close()
+			if _, err := stagePlan(cfg, plan, opts.Silent, share); err != nil {
+				return s.failUpdate(plan.Changes, "下载更新失败: ", err)
+			}
+			s.say("更新已下载，将在维护窗口内安装（使用 --force 立即安装）")
+			return ExitDeferred
+		default:
+			// 启动时检查：窗口外不提示
+			return ExitUpToDate
 		}
-		if needChromePlusUpdate && chromePlusExists {
-			cfg.SkippedChromePlusVersion = latestVersion.ChromePlusVersion
-			configChanged = true
+	}
+
+	if opts.Silent || manual {
+		// 静默模式和手动更新不再询问，浏览器运行中则跳过本次更新
+		if isChromeRunning(cfg) {
+			if opts.Silent {
+				logf("Chrome 正在运行，跳过本次更新")
+			} else {
+				fmt.Fprintln(os.Stderr, "Chrome 正在运行，请关闭浏览器后重试")
+			}
+			return ExitBrowserRunning
+		}
+		for _, c := range plan.Changes {
+			if c.Urgent != "" {
+				s.say("开始紧急更新 %s: %s → %s（%s）", componentName(c.Component), c.From, c.To, c.Urgent)
+			} else {
+				s.say("开始更新 %s: %s → %s", componentName(c.Component), c.From, c.To)
+			}
+		}
+	} else if !state.ChromeExists {
+		// 首次安装（提示手动关闭浏览器）
+		plusVersion := latestVersion.ChromePlusVersion
+		if plusVersion == "" {
+			plusVersion = "暂不可用"
 		}
-		if configChanged {
+		message := fmt.Sprintf("未检测到 Chrome，是否下载安装？\n\n"+
+			"Chrome 版本: %s\n"+
+			"Chrome++ 版本: %s\n\n"+
+			"请确保已关闭所有 Chrome 窗口后点击\"是\"开始安装",
+			latestVersion.ChromeVersion, plusVersion)
+		if !ui.ShowConfirm("ChromeGo 更新", message) {
+			return ExitUpToDate
+		}
+
+		// 用户确认安装，显示控制台窗口
+		ui.ShowConsole()
+	} else {
+		// 逐个组件询问：立即更新、稍后提醒或跳过此版本
+		decisions := s.promptUpdates(plan)
+		if applyDecisions(cfg, plan, decisions, now) {
 			cfg.Save()
 		}
-		return
+		if plan.Empty() {
+			return ExitUpToDate
+		}
+
+		// 用户确认更新，显示控制台窗口
+		ui.ShowConsole()
 	}
 
-	// 用户确认更新，清除跳过的版本记录
-	if needChromeUpdate {
+	// 用户选择可能改变了计划，重新获取变更
+	chromeChange = plan.Change(ComponentChrome)
+	plusChange = plan.Change(ComponentChromePlus)
+
+	// 确认更新，清除跳过的版本和暂停提醒记录
+	if chromeChange != nil {
 		cfg.SkippedChromeVersion = ""
+		cfg.SetSnoozeUntil(ComponentChrome, nil)
+		cfg.SetUpdateSeenAt(ComponentChrome, nil)
 	}
-	if needChromePlusUpdate {
+	if plusChange != nil {
 		cfg.SkippedChromePlusVersion = ""
-	}
-
-	// 用户确认更新，显示控制台窗口
-	consoleHandle := ShowConsole()
-	if consoleHandle != nil && consoleHandle.GetHandle() != 0 {
-		os.Stdout = os.NewFile(consoleHandle.GetHandle(), "stdout")
-		os.Stderr = os.NewFile(consoleHandle.GetHandle(), "stderr")
+		cfg.SetSnoozeUntil(ComponentChromePlus, nil)
+		cfg.SetUpdateSeenAt(ComponentChromePlus, nil)
 	}
 
 	// 执行更新
-	if err := doUpdate(cfg, latestVersion, needChromeUpdate, needChromePlusUpdate); err != nil {
-		ShowError("更新失败: " + err.Error())
-		return
+	share := s.sharePeers()
+	defer share.Close()
+	if err := executePlan(cfg, plan, opts.Silent, share); err != nil {
+		return s.failUpdate(plan.Changes, "更新失败: ", err)
 	}
 
 	// 更新版本号并保存配置
-	if needChromeUpdate {
-		cfg.Version = latestVersion.ChromeVersion
+	if chromeChange != nil {
+		cfg.Version = chromeChange.To
 	}
-	if needChromePlusUpdate {
-		cfg.ChromePlusVersion = latestVersion.ChromePlusVersion
+	if plusChange != nil {
+		cfg.ChromePlusVersion = plusChange.To
 	}
 	if err := cfg.Save(); err != nil {
-		ShowError("保存配置失败: " + err.Error())
-		return
+		return s.failUpdate(plan.Changes, "保存配置失败: ", err)
 	}
+	s.notifyResult(plan.Changes, nil)
 
 	// 创建 Chrome++ 配置快捷方式
-	if needChromePlusUpdate && fileExists(chromePlusIniPath) {
+	if plusChange != nil && fileExists(chromePlusIniPath) {
 		shortcutPath := filepath.Join(baseDir, "Chrome++配置.lnk")
 		if !fileExists(shortcutPath) {
 			CreateShortcut(chromePlusIniPath, shortcutPath, "Chrome++ 配置文件")
@@ -167,80 +416,130 @@ func Run() {
 	}
 
 	// Chrome 更新后清理旧版本
-	if needChromeUpdate {
-		cleanupOldVersions(cfg)
+	if len(plan.Cleanup) > 0 {
+		s.cleanupOldVersions(plan.Cleanup)
 	}
 
 	// 显示完成信息
-	var completeMsg string
-	if needChromeUpdate && needChromePlusUpdate {
-		completeMsg = fmt.Sprintf("Chrome 已更新到 %s\nChrome++ 已更新到 %s",
-			latestVersion.ChromeVersion, latestVersion.ChromePlusVersion)
-	} else if needChromeUpdate {
-		completeMsg = fmt.Sprintf("Chrome 已更新到 %s", latestVersion.ChromeVersion)
-	} else {
-		completeMsg = fmt.Sprintf("Chrome++ 已更新到 %s", latestVersion.ChromePlusVersion)
+	var completeMsg []string
+	for _, c := range plan.Changes {
+		completeMsg = append(completeMsg, fmt.Sprintf("%s 已更新到 %s", componentName(c.Component), c.To))
 	}
-	ShowInfo("更新完成", completeMsg)
+	s.info("更新完成", strings.Join(completeMsg, "\n"))
 
 	// 启动 Chrome
-	startChrome(chromePath)
+	if interactive {
+		startChrome(chromePath)
+	}
+	return ExitUpdated
 }
 
-// cleanupOldVersions 清理旧版本目录
-func cleanupOldVersions(cfg *Config) {
-	appDir := cfg.GetAppDir()
-	keepCount := cfg.GetKeepVersions()
-
-	// 查找版本目录（格式如 123.0.6312.86）
-	entries, err := os.ReadDir(appDir)
-	if err != nil {
-		return
-	}
+// UpdateDecision 用户对单个组件更新的选择
+type UpdateDecision int
 
-	// 版本号正则
-	versionRegex := regexp.MustCompile(`^\d+\.\d+\.\d+\.\d+$`)
-	var versionDirs []string
+const (
+	DecisionUpdate      UpdateDecision = iota // 立即更新
+	DecisionRemindLater                       // 稍后提醒，暂停提醒一段时间
+	DecisionSkip                              // 跳过此版本
+)
 
-	for _, entry := range entries {
-		if entry.IsDir() && versionRegex.MatchString(entry.Name()) {
-			versionDirs = append(versionDirs, entry.Name())
+// promptUpdates 逐个组件询问用户的更新选择
+func (s *session) promptUpdates(plan *UpdatePlan) map[string]UpdateDecision {
+	decisions := make(map[string]UpdateDecision)
+	for _, c := range plan.Changes {
+		name := componentName(c.Component)
+
+		var message string
+		var choices []string
+		if c.Install {
+			message = fmt.Sprintf("%s 未安装，是否安装 %s？\n\n请手动关闭浏览器后选择立即安装", name, c.To)
+			choices = []string{"立即安装", formatSnooze(s.cfg.GetSnooze())}
+		} else if c.Urgent != "" {
+			// 紧急更新：措辞升级，不提供跳过和暂停提醒
+			message = fmt.Sprintf("⚠ %s 重要更新（%s）\n\n%s → %s\n\n"+
+				"根据更新策略，此更新不能跳过，下次启动将再次提示。\n请尽快关闭浏览器并立即更新",
+				name, c.Urgent, c.From, c.To)
+			choices = []string{"立即更新", "本次暂不更新"}
+		} else {
+			from := c.From
+			if from == "" {
+				from = "未知版本"
+			}
+			message = fmt.Sprintf("发现 %s 新版本:\n\n%s → %s\n\n请手动关闭浏览器后选择立即更新", name, from, c.To)
+			choices = []string{"立即更新", formatSnooze(s.cfg.GetSnooze())}
+			if !c.NoSkip {
+				choices = append(choices, "跳过此版本")
+			}
 		}
-	}
 
-	// 如果版本目录数量不超过保留数量，无需清理
-	if len(versionDirs) <= keepCount {
-		return
+		switch s.ui.ShowChoice("ChromeGo 更新", message, choices) {
+		case 0:
+			decisions[c.Component] = DecisionUpdate
+		case 2:
+			decisions[c.Component] = DecisionSkip
+		default:
+			decisions[c.Component] = DecisionRemindLater
+		}
 	}
+	return decisions
+}
 
-	// 按版本号排序（降序，最新的在前）
-	sort.Slice(versionDirs, func(i, j int) bool {
-		return CompareVersion(versionDirs[j], versionDirs[i])
-	})
+// formatSnooze 格式化"稍后提醒"选项文字
+func formatSnooze(d time.Duration) string {
+	return formatDuration(d) + "后提醒"
+}
 
-	// 获取需要删除的旧版本
-	toDelete := versionDirs[keepCount:]
-	if len(toDelete) == 0 {
-		return
+// applyDecisions 按用户选择调整更新计划，只为选择跳过的组件记录跳过版本，
+// 为选择稍后提醒的组件记录暂停截止时间；紧急更新不记录跳过和暂停
+// 返回配置是否被修改
+func applyDecisions(cfg *Config, plan *UpdatePlan, decisions map[string]UpdateDecision, now time.Time) bool {
+	configChanged := false
+	for _, c := range append([]ComponentChange{}, plan.Changes...) {
+		decision := decisions[c.Component]
+		if c.Urgent != "" && decision != DecisionUpdate {
+			plan.Drop(c.Component, "紧急更新，本次暂不更新")
+			continue
+		}
+		switch decision {
+		case DecisionSkip:
+			if c.Component == ComponentChrome {
+				cfg.SkippedChromeVersion = c.To
+			} else {
+				cfg.SkippedChromePlusVersion = c.To
+			}
+			configChanged = true
+			plan.Drop(c.Component, "已跳过此版本")
+		case DecisionRemindLater:
+			until := now.Add(cfg.GetSnooze())
+			cfg.SetSnoozeUntil(c.Component, &until)
+			configChanged = true
+			plan.Drop(c.Component, "稍后提醒")
+		}
 	}
+	return configChanged
+}
+
+// cleanupOldVersions 确认后清理计划中的旧版本目录
+func (s *session) cleanupOldVersions(dirs []string) {
+	baseDir := programDir()
 
 	// 构建确认消息
 	var deleteList []string
-	for _, v := range toDelete {
-		deleteList = append(deleteList, v)
+	for _, dir := range dirs {
+		deleteList = append(deleteList, filepath.Base(dir))
 	}
 
 	message := fmt.Sprintf("发现 %d 个旧版本目录，是否删除？\n\n%s\n\n（将保留最新的 %d 个版本）",
-		len(toDelete), strings.Join(deleteList, "\n"), keepCount)
+		len(dirs), strings.Join(deleteList, "\n"), s.cfg.GetKeepVersions())
 
-	if !ShowConfirm("清理旧版本", message) {
+	if !s.confirm("清理旧版本", message, s.cfg.SilentCleanup) {
 		return
 	}
 
 	// 执行删除
-	for _, v := range toDelete {
-		versionPath := filepath.Join(appDir, v)
-		if err := os.RemoveAll(versionPath); err != nil {
+	for _, dir := range dirs {
+		v := filepath.Base(dir)
+		if err := os.RemoveAll(filepath.Join(baseDir, dir)); err != nil {
 			fmt.Printf("删除 %s 失败: %v\n", v, err)
 		} else {
 			fmt.Printf("已删除旧版本: %s\n", v)
@@ -254,53 +553,143 @@ func fileExists(path string) bool {
 	return err == nil
 }
 
-// doUpdate 执行更新流程
-func doUpdate(cfg *Config, version *VersionInfo, updateChrome, updateChromePlus bool) error {
-	exe, _ := os.Executable()
-	baseDir := filepath.Dir(exe)
-	appDir := filepath.Join(baseDir, cfg.ChromePath)
-	tempDir := filepath.Join(baseDir, "temp")
+// isChromeRunning 检查便携版 Chrome 是否正在运行
+// 运行中的 chrome.exe 及其加载的 version.dll 无法以写方式打开
+func isChromeRunning(cfg *Config) bool {
+	for _, path := range []string{cfg.GetChromePath(), cfg.GetChromePlusDllPath()} {
+		if !fileExists(path) {
+			continue
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY, 0)
+		if err != nil {
+			return true
+		}
+		f.Close()
+	}
+	return false
+}
+
+// executePlan 执行更新计划：下载（或使用已暂存的文件）并解压计划中的组件
+// plain 为 true 时以日志形式输出下载进度，share 不为 nil 时向局域网节点共享校验通过的安装包
+func executePlan(cfg *Config, plan *UpdatePlan, plain bool, share *peerShare) error {
+	packages, err := stagePlan(cfg, plan, plain, share)
+	if err != nil {
+		return err
+	}
+	return applyPlan(cfg, plan, packages, share)
+}
+
+// stagingDir 返回下载暂存目录
+func stagingDir() string {
+	return filepath.Join(programDir(), "staging")
+}
+
+// stagePlan 下载计划中的文件到暂存目录，已暂存且校验通过的文件不再重复下载
+// 有 SHA256 的安装包校验通过后加入 share（可为 nil）共享给局域网节点，返回各组件安装包的路径
+func stagePlan(cfg *Config, plan *UpdatePlan, plain bool, share *peerShare) (map[string]string, error) {
+	dir := stagingDir()
 	threads := cfg.GetThreads()
+	packages := make(map[string]string)
+	var peers []Peer
+	discovered := false
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	for _, d := range plan.Downloads {
+		name := componentName(d.Component)
+		pkg := filepath.Join(dir, d.File)
+
+		// 已暂存的文件校验通过则直接使用
+		if fileExists(pkg) {
+			if err := VerifyFile(pkg, d.Size, d.SHA256); err == nil {
+				fmt.Printf("使用已下载的 %s 安装包\n", name)
+				packages[d.Component] = pkg
+				share.add(d.SHA256, pkg)
+				continue
+			}
+			os.Remove(pkg)
+		}
+
+		// 局域网节点作为额外的下载源（只用于有 SHA256 的安装包，下载后按上游的 SHA256 校验）
+		var peerURLs []string
+		if cfg.PeerDiscovery && d.SHA256 != "" {
+			if !discovered {
+				peers, _ = DiscoverPeers(broadcastAddrs(peerDiscoveryPort), peerDiscoveryTimeout)
+				discovered = true
+			}
+			if peerURLs = PeerURLs(peers, d.SHA256); len(peerURLs) > 0 {
+				fmt.Printf("发现 %d 个局域网节点持有 %s 安装包\n", len(peerURLs), name)
+			}
+		}
+
+		fmt.Printf("使用 %d 线程下载 %s...\n", threads, name)
+		err := downloadPackage(append(peerURLs, d.URLs...), pkg, d, threads, progressPrinter(name, plain))
+		if err != nil && len(peerURLs) > 0 {
+			// 节点提供的内容无法通过校验或下载失败，只从上游重新下载
+			fmt.Printf("从局域网节点下载 %s 失败: %v，改为从上游下载\n", name, err)
+			err = downloadPackage(d.URLs, pkg, d, threads, progressPrinter(name, plain))
+		}
+		if err != nil {
+			return nil, err
+		}
+		packages[d.Component] = pkg
+		share.add(d.SHA256, pkg)
+	}
 
-	// 确保临时目录存在
-	os.MkdirAll(tempDir, 0755)
-	defer os.RemoveAll(tempDir)
+	return packages, nil
+}
+
+// downloadPackage 下载安装包并按计划中的大小和 SHA256 校验，校验通过后才保存为 pkg
+func downloadPackage(urls []string, pkg string, d PlannedDownload, threads int, progress DownloadProgress) error {
+	err := DownloadVerified(urls, pkg, d.Size, d.SHA256, threads, progress)
+	fmt.Println()
+	if err != nil {
+		return fmt.Errorf("下载 %s 失败: %w", componentName(d.Component), err)
+	}
+	return nil
+}
+
+// applyPlan 解压已暂存的安装包完成更新，成功后从 share（可为 nil）中移除并删除暂存文件
+// 解压前备份将被替换的文件，解压失败时恢复并返回 *RolledBackError
+func applyPlan(cfg *Config, plan *UpdatePlan, packages map[string]string, share *peerShare) error {
+	baseDir := programDir()
+	appDir := filepath.Join(baseDir, cfg.ChromePath)
 
 	// 确保 Data 和 Cache 目录存在
 	os.MkdirAll(filepath.Join(baseDir, "Data"), 0755)
 	os.MkdirAll(filepath.Join(baseDir, "Cache"), 0755)
 
-	// 更新 Chrome
-	if updateChrome {
-		chromePkg := filepath.Join(tempDir, "chrome_installer.exe")
-		fmt.Printf("使用 %d 线程下载...\n", threads)
-		if err := DownloadChromeWithProgress(version.ChromeURLs, chromePkg, threads); err != nil {
-			return fmt.Errorf("下载 Chrome 失败: %w", err)
-		}
-		fmt.Println()
+	backup, err := backupFiles(baseDir, backupDir(), plan.Replace)
+	if err != nil {
+		return fmt.Errorf("备份失败: %w", err)
+	}
 
+	// 更新 Chrome
+	if pkg, ok := packages[ComponentChrome]; ok {
 		fmt.Println("正在解压 Chrome...")
-		if err := ExtractChrome(chromePkg, appDir); err != nil {
-			return fmt.Errorf("解压 Chrome 失败: %w", err)
+		if err := ExtractChrome(pkg, appDir); err != nil {
+			return backup.rollback(fmt.Errorf("解压 Chrome 失败: %w", err))
 		}
 		fmt.Println("Chrome 解压完成")
 	}
 
 	// 更新 Chrome++
-	if updateChromePlus && version.ChromePlusURL != "" {
-		plusPkg := filepath.Join(tempDir, "chrome_plus.7z")
-		if err := DownloadFileWithProgress(version.ChromePlusURL, plusPkg, "Chrome++", threads); err != nil {
-			return fmt.Errorf("下载 Chrome++ 失败: %w", err)
-		}
-		fmt.Println()
-
+	if pkg, ok := packages[ComponentChromePlus]; ok {
 		fmt.Println("正在解压 Chrome++...")
-		if err := ExtractChromePlus(plusPkg, appDir); err != nil {
-			return fmt.Errorf("解压 Chrome++ 失败: %w", err)
+		if err := ExtractChromePlus(pkg, appDir); err != nil {
+			return backup.rollback(fmt.Errorf("解压 Chrome++ 失败: %w", err))
 		}
 		fmt.Println("Chrome++ 解压完成")
 	}
+	backup.discard()
 
+	// 先停止共享再删除，避免局域网节点继续从本机下载已删除的安装包
+	for _, pkg := range packages {
+		share.remove(pkg)
+		os.Remove(pkg)
+	}
 	return nil
 }
 
@@ -0,0 +1,302 @@
+package internal
+
+import (
+	"archive/zip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// 测试数据源中的最新版本
+const (
+	testLatestChrome     = "121.0.1.0"
+	testLatestChromePlus = "v1.1.0"
+)
+
+// setupTestInstall 在临时目录中准备已安装的 Chrome 和 Chrome++ 以及本地数据源，
+// 程序目录指向该临时目录，返回目录路径
+func setupTestInstall(t *testing.T, configure func(cfg *Config)) string {
+	t.Helper()
+	dir := t.TempDir()
+
+	oldDir, oldStdout, oldStderr := programDir, os.Stdout, os.Stderr
+	programDir = func() string { return dir }
+	t.Cleanup(func() {
+		programDir = oldDir
+		// 静默模式会将输出重定向到日志文件
+		os.Stdout, os.Stderr = oldStdout, oldStderr
+	})
+
+	// 已安装的版本
+	writeTestFile(t, filepath.Join(dir, "App", "chrome.exe"), "old chrome")
+	writeTestFile(t, filepath.Join(dir, "App", "120.0.1.0", "chrome.dll"), "old dll")
+	writeTestFile(t, filepath.Join(dir, "App", "version.dll"), "old chrome++")
+
+	// 本地数据源
+	source := filepath.Join(dir, "source")
+	chromeZip := filepath.Join(source, "chrome.zip")
+	writeTestZip(t, chromeZip, map[string]string{
+		"Chrome-bin/chrome.exe":                          "new chrome",
+		"Chrome-bin/" + testLatestChrome + "/chrome.dll": "new dll",
+	})
+	plusZip := filepath.Join(source, "Chrome++_"+testLatestChromePlus+".zip")
+	writeTestZip(t, plusZip, map[string]string{
+		"x64/App/version.dll":  "new chrome++",
+		"x64/App/chrome++.ini": "[general]",
+	})
+
+	chromeInfo, err := os.Stat(chromeZip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	chromeData := ChromeData{
+		getChromeDataKey("stable"): {
+			Version: testLatestChrome,
+			Size:    chromeInfo.Size(),
+			SHA256:  testFileSHA256(t, chromeZip),
+			URLs:    []string{chromeZip},
+		},
+	}
+	plusInfo, err := os.Stat(plusZip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	release := GitHubRelease{
+		TagName: testLatestChromePlus,
+		Assets: []Asset{{
+			Name:               filepath.Base(plusZip),
+			Size:               plusInfo.Size(),
+			BrowserDownloadURL: plusZip,
+			Digest:             "sha256:" + testFileSHA256(t, plusZip),
+		}},
+	}
+	writeTestJSON(t, filepath.Join(source, chromeDataFile), chromeData)
+	writeTestJSON(t, filepath.Join(source, chromePlusRelFile), release)
+
+	cfg := DefaultConfig()
+	cfg.Version = "120.0.1.0"
+	cfg.ChromePlusVersion = "v1.0.0"
+	cfg.ChromeDataURL = source
+	cfg.ChromePlusReleaseURL = source
+	if configure != nil {
+		configure(cfg)
+	}
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeTestJSON(t *testing.T, path string, v interface{}) {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, path, string(data))
+}
+
+func writeTestZip(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	out, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	zw := zip.NewWriter(out)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func testFileSHA256(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// loadTestConfig 重新读取保存的配置
+func loadTestConfig(t *testing.T) *Config {
+	t.Helper()
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func readTestFile(t *testing.T, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestRunUpToDate(t *testing.T) {
+	setupTestInstall(t, func(cfg *Config) {
+		cfg.Version = testLatestChrome
+		cfg.ChromePlusVersion = testLatestChromePlus
+	})
+	ui := &ScriptedUI{}
+
+	if code := RunWithUI(nil, ui); code != ExitUpToDate {
+		t.Fatalf("退出码 = %d, want %d", code, ExitUpToDate)
+	}
+	if calls := ui.CallsTo("ShowChoice"); len(calls) != 0 {
+		t.Fatalf("已是最新时不应询问: %+v", calls)
+	}
+	cfg := loadTestConfig(t)
+	if cfg.Version != testLatestChrome || cfg.ChromePlusVersion != testLatestChromePlus {
+		t.Fatalf("版本 = %s / %s", cfg.Version, cfg.ChromePlusVersion)
+	}
+}
+
+func TestRunUpdateAccepted(t *testing.T) {
+	dir := setupTestInstall(t, nil)
+	ui := &ScriptedUI{Choices: []int{0, 0}}
+
+	if code := RunWithUI(nil, ui); code != ExitUpdated {
+		t.Fatalf("退出码 = %d, want %d, 调用: %+v", code, ExitUpdated, ui.Calls)
+	}
+	if calls := ui.CallsTo("ShowChoice"); len(calls) != 2 {
+		t.Fatalf("应逐个组件询问: %+v", calls)
+	}
+	cfg := loadTestConfig(t)
+	if cfg.Version != testLatestChrome || cfg.ChromePlusVersion != testLatestChromePlus {
+		t.Fatalf("版本 = %s / %s", cfg.Version, cfg.ChromePlusVersion)
+	}
+	if got := readTestFile(t, filepath.Join(dir, "App", "chrome.exe")); got != "new chrome" {
+		t.Fatalf("chrome.exe = %q", got)
+	}
+	if got := readTestFile(t, filepath.Join(dir, "App", testLatestChrome, "chrome.dll")); got != "new dll" {
+		t.Fatalf("chrome.dll = %q", got)
+	}
+	if got := readTestFile(t, filepath.Join(dir, "App", "version.dll")); got != "new chrome++" {
+		t.Fatalf("version.dll = %q", got)
+	}
+}
+
+func TestRunSkip(t *testing.T) {
+	dir := setupTestInstall(t, func(cfg *Config) { cfg.ChromePlusVersion = testLatestChromePlus })
+	ui := &ScriptedUI{Choices: []int{2}}
+
+	if code := RunWithUI(nil, ui); code != ExitUpToDate {
+		t.Fatalf("退出码 = %d, want %d", code, ExitUpToDate)
+	}
+	cfg := loadTestConfig(t)
+	if cfg.SkippedChromeVersion != testLatestChrome {
+		t.Fatalf("SkippedChromeVersion = %q", cfg.SkippedChromeVersion)
+	}
+	if cfg.Version != "120.0.1.0" {
+		t.Fatalf("跳过后不应更新, Version = %s", cfg.Version)
+	}
+	if got := readTestFile(t, filepath.Join(dir, "App", "chrome.exe")); got != "old chrome" {
+		t.Fatalf("chrome.exe = %q", got)
+	}
+
+	// 再次启动不再提示已跳过的版本
+	ui = &ScriptedUI{}
+	if code := RunWithUI(nil, ui); code != ExitUpToDate {
+		t.Fatalf("退出码 = %d, want %d", code, ExitUpToDate)
+	}
+	if calls := ui.CallsTo("ShowChoice"); len(calls) != 0 {
+		t.Fatalf("已跳过的版本不应再询问: %+v", calls)
+	}
+}
+
+func TestRunSnooze(t *testing.T) {
+	setupTestInstall(t, func(cfg *Config) { cfg.ChromePlusVersion = testLatestChromePlus })
+	ui := &ScriptedUI{Choices: []int{1}}
+
+	before := time.Now()
+	if code := RunWithUI(nil, ui); code != ExitUpToDate {
+		t.Fatalf("退出码 = %d, want %d", code, ExitUpToDate)
+	}
+	cfg := loadTestConfig(t)
+	until := cfg.SnoozeUntil(ComponentChrome)
+	if until == nil || until.Before(before.Add(cfg.GetSnooze()-time.Minute)) {
+		t.Fatalf("ChromeSnoozeUntil = %v", until)
+	}
+	if cfg.SkippedChromeVersion != "" || cfg.Version != "120.0.1.0" {
+		t.Fatalf("稍后提醒不应跳过或更新: %+v", cfg)
+	}
+}
+
+func TestRunSilent(t *testing.T) {
+	dir := setupTestInstall(t, func(cfg *Config) { cfg.SkippedChromeVersion = testLatestChrome })
+	ui := &ScriptedUI{}
+
+	if code := RunWithUI([]string{"--silent"}, ui); code != ExitUpdated {
+		t.Fatalf("退出码 = %d, want %d", code, ExitUpdated)
+	}
+	if len(ui.Calls) != 0 {
+		t.Fatalf("静默模式不应调用界面: %+v", ui.Calls)
+	}
+	cfg := loadTestConfig(t)
+	if cfg.Version != testLatestChrome || cfg.ChromePlusVersion != testLatestChromePlus {
+		t.Fatalf("版本 = %s / %s", cfg.Version, cfg.ChromePlusVersion)
+	}
+	if cfg.SkippedChromeVersion != "" {
+		t.Fatalf("更新后应清除跳过的版本: %q", cfg.SkippedChromeVersion)
+	}
+	if got := readTestFile(t, filepath.Join(dir, "App", "chrome.exe")); got != "new chrome" {
+		t.Fatalf("chrome.exe = %q", got)
+	}
+	if _, err := os.Stat(cfg.GetLogFile()); err != nil {
+		t.Fatalf("静默模式应写入日志: %v", err)
+	}
+}
+
+func TestRunChromePlusDigestMismatch(t *testing.T) {
+	dir := setupTestInstall(t, func(cfg *Config) { cfg.Version = testLatestChrome })
+	releasePath := filepath.Join(dir, "source", chromePlusRelFile)
+	var release GitHubRelease
+	if err := json.Unmarshal([]byte(readTestFile(t, releasePath)), &release); err != nil {
+		t.Fatal(err)
+	}
+	release.Assets[0].Digest = "sha256:" + strings.Repeat("0", 64)
+	writeTestJSON(t, releasePath, release)
+
+	if code := RunWithUI([]string{"--silent"}, &ScriptedUI{}); code != ExitFailed {
+		t.Fatalf("退出码 = %d, want %d", code, ExitFailed)
+	}
+	if got := readTestFile(t, filepath.Join(dir, "App", "version.dll")); got != "old chrome++" {
+		t.Fatalf("SHA256 不符时不应安装, version.dll = %q", got)
+	}
+	if cfg := loadTestConfig(t); cfg.ChromePlusVersion != "v1.0.0" {
+		t.Fatalf("ChromePlusVersion = %s", cfg.ChromePlusVersion)
+	}
+}
@@ -0,0 +1,160 @@
+package internal
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// HostAuth 主机认证配置，只用于发往该主机的请求（跳转到其他主机时不携带）
+// 密码和 Token 不直接写在 config.json 中，而是从环境变量或凭据文件读取
+type HostAuth struct {
+	Host        string            `json:"host"`         // 主机名，可带端口；"*.example.com" 匹配所有子域名
+	Username    string            `json:"username"`     // Basic 认证用户名
+	PasswordEnv string            `json:"password_env"` // Basic 认证密码所在的环境变量
+	TokenEnv    string            `json:"token_env"`    // Bearer Token 所在的环境变量
+	Headers     map[string]string `json:"headers"`      // 附加请求头，值中可用 ${VAR} 引用环境变量
+}
+
+// Credential 解析后的主机凭据
+type Credential struct {
+	Username string            `json:"username"`
+	Password string            `json:"password"`
+	Token    string            `json:"token"`
+	Headers  map[string]string `json:"headers"`
+}
+
+// credentialsFile 凭据文件结构，按主机保存凭据
+type credentialsFile struct {
+	Hosts map[string]Credential `json:"hosts"`
+}
+
+// authTransport 按请求的主机添加认证信息
+// 认证信息在每次发送时按目标主机添加，跳转后的请求同样经过此处，因此不会带到未配置的主机
+type authTransport struct {
+	base http.RoundTripper
+
+	mu    sync.RWMutex
+	creds map[string]Credential
+}
+
+// httpClient 所有元数据和下载请求共用的 HTTP 客户端
+var httpClient = &http.Client{Transport: defaultTransport}
+
+// defaultTransport 共用客户端的认证传输层
+var defaultTransport = &authTransport{base: newBaseTransport()}
+
+// ConfigureHTTP 根据配置加载主机凭据
+func ConfigureHTTP(cfg *Config) error {
+	creds, err := loadCredentials(cfg)
+	defaultTransport.mu.Lock()
+	defaultTransport.creds = creds
+	defaultTransport.mu.Unlock()
+	return err
+}
+
+// loadCredentials 合并配置中的主机认证和凭据文件，凭据文件中的值优先
+func loadCredentials(cfg *Config) (map[string]Credential, error) {
+	creds := make(map[string]Credential)
+	for _, a := range cfg.HostAuth {
+		host := strings.ToLower(strings.TrimSpace(a.Host))
+		if host == "" {
+			continue
+		}
+		c := Credential{Username: a.Username}
+		if a.PasswordEnv != "" {
+			c.Password = os.Getenv(a.PasswordEnv)
+		}
+		if a.TokenEnv != "" {
+			c.Token = os.Getenv(a.TokenEnv)
+		}
+		if len(a.Headers) > 0 {
+			c.Headers = make(map[string]string, len(a.Headers))
+			for k, v := range a.Headers {
+				c.Headers[k] = os.ExpandEnv(v)
+			}
+		}
+		creds[host] = c
+	}
+
+	path := cfg.GetCredentialsFile()
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return creds, nil
+	}
+	if err != nil {
+		return creds, err
+	}
+	var file credentialsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return creds, err
+	}
+	for host, fc := range file.Hosts {
+		host = strings.ToLower(strings.TrimSpace(host))
+		c := creds[host]
+		if fc.Username != "" {
+			c.Username = fc.Username
+		}
+		if fc.Password != "" {
+			c.Password = fc.Password
+		}
+		if fc.Token != "" {
+			c.Token = fc.Token
+		}
+		for k, v := range fc.Headers {
+			if c.Headers == nil {
+				c.Headers = make(map[string]string)
+			}
+			c.Headers[k] = v
+		}
+		creds[host] = c
+	}
+	return creds, nil
+}
+
+// lookup 查找主机的凭据：先匹配 host:port，再匹配主机名，最后匹配通配符
+func (t *authTransport) lookup(hostport, hostname string) (Credential, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	if c, ok := t.creds[strings.ToLower(hostport)]; ok {
+		return c, true
+	}
+	hostname = strings.ToLower(hostname)
+	if c, ok := t.creds[hostname]; ok {
+		return c, true
+	}
+	for pattern, c := range t.creds {
+		if strings.HasPrefix(pattern, "*.") && strings.HasSuffix(hostname, pattern[1:]) {
+			return c, true
+		}
+	}
+	return Credential{}, false
+}
+
+// RoundTrip 为配置了凭据的主机添加认证信息
+// 凭据只通过 https 发送，http 地址即使主机匹配也不携带，避免以明文泄露
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.URL.Scheme != "https" {
+		return t.base.RoundTrip(req)
+	}
+	c, ok := t.lookup(req.URL.Host, req.URL.Hostname())
+	if !ok {
+		return t.base.RoundTrip(req)
+	}
+
+	// RoundTrip 不能修改原请求
+	req = req.Clone(req.Context())
+	switch {
+	case c.Token != "":
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	case c.Username != "":
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+	for k, v := range c.Headers {
+		req.Header.Set(k, v)
+	}
+	return t.base.RoundTrip(req)
+}
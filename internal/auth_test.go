@@ -0,0 +1,121 @@
+package internal
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+// newAuthRecorder 返回记录每次请求 Authorization 和附加请求头的服务处理函数
+func newAuthRecorder() (http.HandlerFunc, func() []string) {
+	var mu sync.Mutex
+	var seen []string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen = append(seen, r.Header.Get("Authorization")+"|"+r.Header.Get("X-Api-Key"))
+		mu.Unlock()
+	}
+	return handler, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string{}, seen...)
+	}
+}
+
+// newTestAuthClient 返回使用指定凭据的客户端，接受测试服务的自签名证书
+func newTestAuthClient(creds map[string]Credential) *http.Client {
+	base := http.DefaultTransport.(*http.Transport).Clone()
+	base.TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
+	return &http.Client{Transport: &authTransport{base: base, creds: creds}}
+}
+
+func TestAuthTransportRedirect(t *testing.T) {
+	otherHandler, otherSeen := newAuthRecorder()
+	other := httptest.NewTLSServer(otherHandler)
+	t.Cleanup(other.Close)
+
+	mirrorHandler, mirrorSeen := newAuthRecorder()
+	mirror := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mirrorHandler(w, r)
+		http.Redirect(w, r, other.URL+"/chrome.zip", http.StatusFound)
+	}))
+	t.Cleanup(mirror.Close)
+
+	// 两个服务的主机名相同，只按 host:port 配置镜像
+	mirrorHost := mirror.Listener.Addr().String()
+	client := newTestAuthClient(map[string]Credential{
+		mirrorHost: {Token: "secret", Headers: map[string]string{"X-Api-Key": "key"}},
+	})
+
+	resp, err := client.Get(mirror.URL + "/chrome.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if got := mirrorSeen(); len(got) != 1 || got[0] != "Bearer secret|key" {
+		t.Fatalf("镜像收到的认证信息 = %q", got)
+	}
+	if got := otherSeen(); len(got) != 1 || got[0] != "|" {
+		t.Fatalf("跳转到未配置的主机时不应携带认证信息, 收到 %q", got)
+	}
+}
+
+func TestAuthTransportPlainHTTP(t *testing.T) {
+	handler, seen := newAuthRecorder()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := newTestAuthClient(map[string]Credential{
+		u.Host: {Username: "chromego", Password: "secret", Headers: map[string]string{"X-Api-Key": "key"}},
+	})
+	resp, err := client.Get(server.URL + "/data.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if got := seen(); len(got) != 1 || got[0] != "|" {
+		t.Fatalf("http 地址不应携带认证信息, 收到 %q", got)
+	}
+}
+
+func TestAuthTransportLookup(t *testing.T) {
+	transport := &authTransport{creds: map[string]Credential{
+		"mirror.lan:8443":     {Token: "port"},
+		"mirror.lan":          {Token: "host"},
+		"*.artifacts.example": {Token: "wildcard"},
+	}}
+
+	tests := []struct {
+		name      string
+		hostport  string
+		hostname  string
+		wantToken string // 为空表示不应匹配
+	}{
+		{name: "优先匹配端口", hostport: "mirror.lan:8443", hostname: "mirror.lan", wantToken: "port"},
+		{name: "其他端口匹配主机名", hostport: "mirror.lan:443", hostname: "mirror.lan", wantToken: "host"},
+		{name: "主机名不区分大小写", hostport: "Mirror.LAN", hostname: "Mirror.LAN", wantToken: "host"},
+		{name: "通配符匹配子域名", hostport: "cdn.artifacts.example", hostname: "cdn.artifacts.example", wantToken: "wildcard"},
+		{name: "通配符匹配多级子域名", hostport: "a.b.artifacts.example:443", hostname: "a.b.artifacts.example", wantToken: "wildcard"},
+		{name: "通配符不匹配根域名", hostport: "artifacts.example", hostname: "artifacts.example"},
+		{name: "通配符不匹配相同后缀的其他域名", hostport: "evilartifacts.example", hostname: "evilartifacts.example"},
+		{name: "未配置的主机", hostport: "github.com", hostname: "github.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, ok := transport.lookup(tt.hostport, tt.hostname)
+			if ok != (tt.wantToken != "") || c.Token != tt.wantToken {
+				t.Fatalf("lookup = %+v, %v, want token %q", c, ok, tt.wantToken)
+			}
+		})
+	}
+}
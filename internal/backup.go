@@ -0,0 +1,110 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// RolledBackError 更新失败，已恢复到更新前的文件
+type RolledBackError struct {
+	Err error
+}
+
+func (e *RolledBackError) Error() string {
+	return e.Err.Error() + "（已恢复到更新前的版本）"
+}
+
+func (e *RolledBackError) Unwrap() error {
+	return e.Err
+}
+
+// fileBackup 更新前备份将被替换的文件，更新失败时恢复
+type fileBackup struct {
+	baseDir string   // 程序目录
+	dir     string   // 备份目录
+	saved   []string // 已备份的路径（相对程序目录）
+	created []string // 更新前不存在的路径，恢复时删除
+}
+
+// backupDir 返回更新备份目录
+func backupDir() string {
+	return filepath.Join(programDir(), "backup")
+}
+
+// backupFiles 备份程序目录下将被替换的文件和目录
+func backupFiles(baseDir, dir string, paths []string) (*fileBackup, error) {
+	os.RemoveAll(dir)
+	b := &fileBackup{baseDir: baseDir, dir: dir}
+	for _, p := range paths {
+		src := filepath.Join(baseDir, p)
+		info, err := os.Stat(src)
+		if os.IsNotExist(err) {
+			b.created = append(b.created, p)
+			continue
+		}
+		if err != nil {
+			b.discard()
+			return nil, err
+		}
+
+		dst := filepath.Join(dir, p)
+		if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+			b.discard()
+			return nil, err
+		}
+		if info.IsDir() {
+			err = copyDir(src, dst)
+		} else {
+			err = copyFile(src, dst)
+		}
+		if err != nil {
+			b.discard()
+			return nil, fmt.Errorf("备份 %s 失败: %w", p, err)
+		}
+		b.saved = append(b.saved, p)
+	}
+	return b, nil
+}
+
+// restore 恢复备份的文件，删除更新前不存在的文件
+func (b *fileBackup) restore() error {
+	for _, p := range b.created {
+		if err := os.RemoveAll(filepath.Join(b.baseDir, p)); err != nil {
+			return err
+		}
+	}
+	for _, p := range b.saved {
+		src := filepath.Join(b.dir, p)
+		dst := filepath.Join(b.baseDir, p)
+		info, err := os.Stat(src)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			os.RemoveAll(dst)
+			err = copyDir(src, dst)
+		} else {
+			err = copyFile(src, dst)
+		}
+		if err != nil {
+			return fmt.Errorf("恢复 %s 失败: %w", p, err)
+		}
+	}
+	b.discard()
+	return nil
+}
+
+// rollback 更新失败时恢复备份，恢复成功返回 *RolledBackError
+func (b *fileBackup) rollback(err error) error {
+	fmt.Println("更新失败，正在恢复更新前的文件...")
+	if restoreErr := b.restore(); restoreErr != nil {
+		return fmt.Errorf("%w；恢复备份失败: %v，备份保存在 %s", err, restoreErr, b.dir)
+	}
+	return &RolledBackError{Err: err}
+}
+
+// discard 删除备份
+func (b *fileBackup) discard() {
+	os.RemoveAll(b.dir)
+}
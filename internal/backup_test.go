@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileBackupRollback(t *testing.T) {
+	base := t.TempDir()
+	backup := filepath.Join(base, "backup")
+	writeTestFile(t, filepath.Join(base, "App", "chrome.exe"), "old chrome")
+	writeTestFile(t, filepath.Join(base, "App", "120.0.1.0", "chrome.dll"), "old dll")
+
+	b, err := backupFiles(base, backup, []string{"App/chrome.exe", "App/120.0.1.0", "App/121.0.1.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 模拟更新到一半失败
+	writeTestFile(t, filepath.Join(base, "App", "chrome.exe"), "new chrome")
+	writeTestFile(t, filepath.Join(base, "App", "120.0.1.0", "chrome.dll"), "broken")
+	writeTestFile(t, filepath.Join(base, "App", "120.0.1.0", "extra.dll"), "extra")
+	writeTestFile(t, filepath.Join(base, "App", "121.0.1.0", "chrome.dll"), "new dll")
+
+	cause := errors.New("解压失败")
+	err = b.rollback(cause)
+	var rolledBack *RolledBackError
+	if !errors.As(err, &rolledBack) || !errors.Is(err, cause) {
+		t.Fatalf("err = %v, want *RolledBackError 包装原因", err)
+	}
+
+	if got := readTestFile(t, filepath.Join(base, "App", "chrome.exe")); got != "old chrome" {
+		t.Fatalf("chrome.exe = %q", got)
+	}
+	if got := readTestFile(t, filepath.Join(base, "App", "120.0.1.0", "chrome.dll")); got != "old dll" {
+		t.Fatalf("chrome.dll = %q", got)
+	}
+	for _, path := range []string{"App/120.0.1.0/extra.dll", "App/121.0.1.0", "backup"} {
+		if fileExists(filepath.Join(base, path)) {
+			t.Fatalf("恢复后不应存在 %s", path)
+		}
+	}
+}
+
+func TestFileBackupDiscard(t *testing.T) {
+	base := t.TempDir()
+	backup := filepath.Join(base, "backup")
+	writeTestFile(t, filepath.Join(base, "App", "version.dll"), "old chrome++")
+
+	b, err := backupFiles(base, backup, []string{"App/version.dll"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := readTestFile(t, filepath.Join(backup, "App", "version.dll")); got != "old chrome++" {
+		t.Fatalf("备份内容 = %q", got)
+	}
+
+	writeTestFile(t, filepath.Join(base, "App", "version.dll"), "new chrome++")
+	b.discard()
+	if fileExists(backup) {
+		t.Fatal("discard 后应删除备份")
+	}
+	if got := readTestFile(t, filepath.Join(base, "App", "version.dll")); got != "new chrome++" {
+		t.Fatalf("discard 不应修改已更新的文件, version.dll = %q", got)
+	}
+}
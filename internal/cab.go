@@ -0,0 +1,290 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// CAB 压缩方式（CFFOLDER.typeCompress 的低 4 位）
+const (
+	cabCompressNone    = 0
+	cabCompressMSZIP   = 1
+	cabCompressQuantum = 2
+	cabCompressLZX     = 3
+)
+
+// CAB 头部标志
+const (
+	cabFlagPrevCabinet    = 0x0001
+	cabFlagNextCabinet    = 0x0002
+	cabFlagReservePresent = 0x0004
+)
+
+// errCabLZX CAB 使用不支持的 LZX 压缩
+var errCabLZX = errors.New("不支持 LZX 压缩的 CAB")
+
+// cabBlockSize MSZIP 数据块解压后的最大长度，也是下一块的预置字典长度
+const cabBlockSize = 32768
+
+// cabFolder CAB 中的数据段
+type cabFolder struct {
+	start    int64 // 第一个数据块在 CAB 中的偏移
+	blocks   int
+	compress uint16
+}
+
+// cabFile CAB 中的文件
+type cabFile struct {
+	name   string
+	size   uint32
+	offset uint32 // 在所属数据段解压后的偏移
+	folder uint16
+}
+
+// extractCab 顺序读取 CAB 文件（MSCF），将其中的文件解压到目录，返回解压出的文件路径
+// 支持未压缩和 MSZIP 压缩（LZX 压缩返回 errCabLZX），不支持跨多个 CAB 的文件
+func extractCab(r io.Reader, destDir string) ([]string, error) {
+	br := bufio.NewReader(r)
+	cr := &countingReader{r: br}
+
+	var header struct {
+		Signature  [4]byte
+		Reserved1  uint32
+		Size       uint32
+		Reserved2  uint32
+		FilesStart uint32
+		Reserved3  uint32
+		Minor      uint8
+		Major      uint8
+		Folders    uint16
+		Files      uint16
+		Flags      uint16
+		SetID      uint16
+		Cabinet    uint16
+	}
+	if err := binary.Read(cr, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf("读取 CAB 头失败: %w", err)
+	}
+	if string(header.Signature[:]) != "MSCF" {
+		return nil, fmt.Errorf("不是 CAB 文件")
+	}
+	if header.Flags&(cabFlagPrevCabinet|cabFlagNextCabinet) != 0 {
+		return nil, fmt.Errorf("不支持分卷 CAB")
+	}
+
+	var folderReserve, dataReserve int
+	if header.Flags&cabFlagReservePresent != 0 {
+		var reserve struct {
+			Header uint16
+			Folder uint8
+			Data   uint8
+		}
+		if err := binary.Read(cr, binary.LittleEndian, &reserve); err != nil {
+			return nil, err
+		}
+		if err := cr.skip(int64(reserve.Header)); err != nil {
+			return nil, err
+		}
+		folderReserve, dataReserve = int(reserve.Folder), int(reserve.Data)
+	}
+
+	folders := make([]cabFolder, header.Folders)
+	for i := range folders {
+		var entry struct {
+			DataStart uint32
+			Blocks    uint16
+			Compress  uint16
+		}
+		if err := binary.Read(cr, binary.LittleEndian, &entry); err != nil {
+			return nil, fmt.Errorf("读取 CAB 数据段失败: %w", err)
+		}
+		if err := cr.skip(int64(folderReserve)); err != nil {
+			return nil, err
+		}
+		folders[i] = cabFolder{start: int64(entry.DataStart), blocks: int(entry.Blocks), compress: entry.Compress & 0x000f}
+	}
+
+	if err := cr.skip(int64(header.FilesStart) - cr.n); err != nil {
+		return nil, err
+	}
+	files := make([]cabFile, header.Files)
+	for i := range files {
+		var entry struct {
+			Size    uint32
+			Offset  uint32
+			Folder  uint16
+			Date    uint16
+			Time    uint16
+			Attribs uint16
+		}
+		if err := binary.Read(cr, binary.LittleEndian, &entry); err != nil {
+			return nil, fmt.Errorf("读取 CAB 文件列表失败: %w", err)
+		}
+		name, err := br.ReadString(0)
+		if err != nil {
+			return nil, fmt.Errorf("读取 CAB 文件名失败: %w", err)
+		}
+		cr.n += int64(len(name))
+		if int(entry.Folder) >= len(folders) {
+			return nil, fmt.Errorf("不支持分卷 CAB")
+		}
+		files[i] = cabFile{name: name[:len(name)-1], size: entry.Size, offset: entry.Offset, folder: entry.Folder}
+	}
+
+	// 顺序读取，数据段需按偏移递增排列
+	var paths []string
+	for i, folder := range folders {
+		switch folder.compress {
+		case cabCompressNone, cabCompressMSZIP:
+		case cabCompressQuantum:
+			return nil, fmt.Errorf("不支持 Quantum 压缩的 CAB")
+		case cabCompressLZX:
+			return nil, errCabLZX
+		default:
+			return nil, fmt.Errorf("未知的 CAB 压缩方式: %d", folder.compress)
+		}
+
+		var members []cabFile
+		for _, f := range files {
+			if int(f.folder) == i {
+				members = append(members, f)
+			}
+		}
+		sort.Slice(members, func(a, b int) bool { return members[a].offset < members[b].offset })
+
+		if err := cr.skip(folder.start - cr.n); err != nil {
+			return nil, err
+		}
+		data := &cabFolderReader{r: cr, folder: folder, reserve: dataReserve}
+		var pos int64
+		for _, f := range members {
+			if int64(f.offset) < pos {
+				return nil, fmt.Errorf("CAB 中的文件 %s 与其他文件重叠", f.name)
+			}
+			if _, err := io.CopyN(io.Discard, data, int64(f.offset)-pos); err != nil {
+				return nil, fmt.Errorf("读取 CAB 数据失败: %w", err)
+			}
+			path := filepath.Join(destDir, resourceFileName(f.name))
+			if err := writeCabFile(path, data, int64(f.size)); err != nil {
+				return nil, fmt.Errorf("解压 %s 失败: %w", f.name, err)
+			}
+			paths = append(paths, path)
+			pos = int64(f.offset) + int64(f.size)
+		}
+		// 跳过数据段中剩余的数据块
+		if _, err := io.Copy(io.Discard, data); err != nil {
+			return nil, fmt.Errorf("读取 CAB 数据失败: %w", err)
+		}
+	}
+	return paths, nil
+}
+
+// writeCabFile 从数据段中读取 size 字节写入文件
+func writeCabFile(path string, r io.Reader, size int64) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	if _, err := io.CopyN(out, r, size); err != nil {
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+		return err
+	}
+	return nil
+}
+
+// cabFolderReader 依次解压数据段中的数据块
+type cabFolderReader struct {
+	r       *countingReader
+	folder  cabFolder
+	reserve int
+	read    int    // 已读取的数据块数
+	buf     []byte // 当前块未读出的数据
+	window  []byte // MSZIP 上一块解压后的数据，作为下一块的字典
+}
+
+func (c *cabFolderReader) Read(p []byte) (int, error) {
+	for len(c.buf) == 0 {
+		if c.read >= c.folder.blocks {
+			return 0, io.EOF
+		}
+		if err := c.next(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, c.buf)
+	c.buf = c.buf[n:]
+	return n, nil
+}
+
+// next 读取并解压下一个数据块
+func (c *cabFolderReader) next() error {
+	var header struct {
+		Checksum     uint32
+		Compressed   uint16
+		Uncompressed uint16
+	}
+	if err := binary.Read(c.r, binary.LittleEndian, &header); err != nil {
+		return err
+	}
+	if err := c.r.skip(int64(c.reserve)); err != nil {
+		return err
+	}
+	raw := make([]byte, header.Compressed)
+	if _, err := io.ReadFull(c.r, raw); err != nil {
+		return err
+	}
+	c.read++
+
+	if c.folder.compress == cabCompressNone {
+		c.buf = raw
+		return nil
+	}
+
+	// MSZIP: "CK" + deflate 数据，每块以上一块的解压结果为字典
+	if len(raw) < 2 || raw[0] != 'C' || raw[1] != 'K' {
+		return fmt.Errorf("MSZIP 数据块签名无效")
+	}
+	if header.Uncompressed > cabBlockSize {
+		return fmt.Errorf("MSZIP 数据块过大")
+	}
+	fr := flate.NewReaderDict(bytes.NewReader(raw[2:]), c.window)
+	out := make([]byte, header.Uncompressed)
+	if _, err := io.ReadFull(fr, out); err != nil {
+		return fmt.Errorf("MSZIP 解压失败: %w", err)
+	}
+	c.window = out
+	c.buf = out
+	return nil
+}
+
+// countingReader 记录已读取字节数，用于按头部中的偏移跳转
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// skip 跳过 n 字节，n 为负表示头部偏移无效
+func (c *countingReader) skip(n int64) error {
+	if n < 0 {
+		return fmt.Errorf("CAB 头部偏移无效")
+	}
+	_, err := io.CopyN(io.Discard, c, n)
+	return err
+}
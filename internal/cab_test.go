@@ -0,0 +1,185 @@
+package internal
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// cabTestFile 测试 CAB 中的文件
+type cabTestFile struct {
+	name   string
+	body   []byte
+	offset int // 文件记录中的偏移，0 表示紧接上一个文件
+}
+
+// buildTestCab 构造只有一个数据段的 CAB，文件内容依次拼接，按 cabBlockSize 分块（MSZIP 每块以上一块为字典）
+func buildTestCab(compress uint16, files []cabTestFile) []byte {
+	var folderData []byte
+	for _, f := range files {
+		folderData = append(folderData, f.body...)
+	}
+
+	var blocks bytes.Buffer
+	count := 0
+	var window []byte
+	for start := 0; start < len(folderData) || count == 0; start += cabBlockSize {
+		end := start + cabBlockSize
+		if end > len(folderData) {
+			end = len(folderData)
+		}
+		chunk := folderData[start:end]
+		raw := chunk
+		if compress == cabCompressMSZIP {
+			var buf bytes.Buffer
+			buf.WriteString("CK")
+			w, _ := flate.NewWriterDict(&buf, flate.BestCompression, window)
+			w.Write(chunk)
+			w.Close()
+			raw = buf.Bytes()
+			window = chunk
+		}
+		binary.Write(&blocks, binary.LittleEndian, struct {
+			Checksum     uint32
+			Compressed   uint16
+			Uncompressed uint16
+		}{0, uint16(len(raw)), uint16(len(chunk))})
+		blocks.Write(raw)
+		count++
+	}
+
+	var fileList bytes.Buffer
+	pos := 0
+	for _, f := range files {
+		offset := pos
+		if f.offset != 0 {
+			offset = f.offset
+		}
+		binary.Write(&fileList, binary.LittleEndian, struct {
+			Size, Offset                uint32
+			Folder, Date, Time, Attribs uint16
+		}{uint32(len(f.body)), uint32(offset), 0, 0, 0, 0})
+		fileList.WriteString(f.name)
+		fileList.WriteByte(0)
+		pos += len(f.body)
+	}
+
+	const headerSize, folderSize = 36, 8
+	filesStart := headerSize + folderSize
+	dataStart := filesStart + fileList.Len()
+
+	var out bytes.Buffer
+	out.WriteString("MSCF")
+	binary.Write(&out, binary.LittleEndian, struct {
+		Reserved1, Size, Reserved2, FilesStart, Reserved3 uint32
+		Minor, Major                                      uint8
+		Folders, Files, Flags, SetID, Cabinet             uint16
+	}{0, uint32(dataStart + blocks.Len()), 0, uint32(filesStart), 0, 3, 1, 1, uint16(len(files)), 0, 0, 0})
+	binary.Write(&out, binary.LittleEndian, struct {
+		DataStart        uint32
+		Blocks, Compress uint16
+	}{uint32(dataStart), uint16(count), compress})
+	out.Write(fileList.Bytes())
+	out.Write(blocks.Bytes())
+	return out.Bytes()
+}
+
+// cabTestData 返回有重复内容的测试数据，MSZIP 压缩时后面的块会引用前一块
+func cabTestData(size int) []byte {
+	var buf bytes.Buffer
+	for i := 0; buf.Len() < size; i++ {
+		fmt.Fprintf(&buf, "line %d of chrome.7z\n", i%1000)
+	}
+	return buf.Bytes()[:size]
+}
+
+func TestExtractCab(t *testing.T) {
+	large := cabTestData(3*cabBlockSize + 1000)
+
+	tests := []struct {
+		name     string
+		compress uint16
+		files    []cabTestFile
+	}{
+		{name: "未压缩", compress: cabCompressNone, files: []cabTestFile{{name: "chrome.7z", body: []byte("payload")}}},
+		{name: "MSZIP 单块", compress: cabCompressMSZIP, files: []cabTestFile{{name: "chrome.7z", body: []byte("payload")}}},
+		{name: "MSZIP 多块", compress: cabCompressMSZIP, files: []cabTestFile{{name: "chrome.7z", body: large}}},
+		{
+			name:     "MSZIP 多个文件",
+			compress: cabCompressMSZIP,
+			files: []cabTestFile{
+				{name: `bin\setup.exe`, body: cabTestData(cabBlockSize + 10)},
+				{name: "chrome.7z", body: large},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			paths, err := extractCab(bytes.NewReader(buildTestCab(tt.compress, tt.files)), dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(paths) != len(tt.files) {
+				t.Fatalf("paths = %v", paths)
+			}
+			for i, f := range tt.files {
+				want := filepath.Join(dir, resourceFileName(f.name))
+				if paths[i] != want {
+					t.Fatalf("paths[%d] = %s, want %s", i, paths[i], want)
+				}
+				if got := readTestFile(t, want); got != string(f.body) {
+					t.Fatalf("%s 内容不一致（%d 字节, want %d 字节）", f.name, len(got), len(f.body))
+				}
+			}
+		})
+	}
+}
+
+func TestExtractCabErrors(t *testing.T) {
+	valid := buildTestCab(cabCompressMSZIP, []cabTestFile{{name: "chrome.7z", body: cabTestData(cabBlockSize + 10)}})
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr string
+	}{
+		{name: "签名无效", data: append([]byte("MSCX"), valid[4:]...), wantErr: "不是 CAB 文件"},
+		{name: "头部截断", data: valid[:20], wantErr: "读取 CAB 头失败"},
+		{name: "文件列表截断", data: valid[:46], wantErr: "读取 CAB 文件列表失败"},
+		{name: "数据截断", data: valid[:len(valid)-10], wantErr: "解压 chrome.7z 失败"},
+		{
+			name: "文件重叠",
+			data: buildTestCab(cabCompressNone, []cabTestFile{
+				{name: "a.bin", body: []byte("aaaa")},
+				{name: "b.bin", body: []byte("bbbb"), offset: 2},
+			}),
+			wantErr: "重叠",
+		},
+		{
+			name:    "分卷",
+			data:    func() []byte { d := bytes.Clone(valid); d[30] = cabFlagNextCabinet; return d }(),
+			wantErr: "不支持分卷 CAB",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := extractCab(bytes.NewReader(tt.data), t.TempDir())
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	lzx := buildTestCab(cabCompressLZX|0x1500, []cabTestFile{{name: "chrome.7z", body: []byte("x")}})
+	if _, err := extractCab(bytes.NewReader(lzx), t.TempDir()); !errors.Is(err, errCabLZX) {
+		t.Fatalf("LZX: err = %v, want errCabLZX", err)
+	}
+}
@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// 进程退出码，便于登录脚本、计划任务判断执行结果
+const (
+	ExitUpToDate       = 0 // 已是最新版本（或用户未选择更新）
+	ExitFailed         = 1 // 检查或更新失败
+	ExitUpdated        = 2 // 已完成更新
+	ExitBrowserRunning = 3 // 浏览器正在运行，跳过更新
+	ExitDeferred       = 4 // 不在维护窗口内，已下载更新，等待维护窗口安装
+)
+
+// 子命令
+const (
+	CommandUpdate  = "update"  // 手动检查并立即更新，忽略已跳过、暂停提醒的版本和最短发布时长
+	CommandCheck   = "check"   // 忽略元数据缓存检查更新，只输出更新计划
+	CommandInstall = "install" // 从本地安装包离线安装，不联网
+	CommandServe   = "serve"   // 运行局域网缓存服务
+	CommandMirror  = "mirror"  // 生成离线镜像目录
+	CommandSign    = "sign"    // 对元数据签名或生成签名密钥
+)
+
+// Options 命令行选项
+type Options struct {
+	Command string // 子命令，为空表示启动浏览器并检查更新
+	Silent  bool   // 静默模式：不弹窗，按配置自动应答，输出写入日志文件
+	DryRun  bool   // 预演模式：只输出更新计划，不做任何修改
+	JSON    bool   // 预演模式下以 JSON 输出更新计划
+	Force   bool   // 忽略维护窗口，立即安装更新
+	From    string // install 命令：本地安装包路径
+	SHA256  string // install 命令：安装包的 SHA256，为空时不校验
+	Listen  string // serve 命令：监听地址
+	Mirror  MirrorOptions
+	Key     string   // sign/serve/mirror 命令：签名私钥文件
+	Keygen  bool     // sign 命令：生成密钥对
+	Args    []string // 选项之后的其余参数
+}
+
+// ParseOptions 解析命令行参数
+func ParseOptions(args []string) (*Options, error) {
+	opts := &Options{}
+
+	// 第一个非选项参数为子命令
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		opts.Command = args[0]
+		args = args[1:]
+		if !isCommand(opts.Command) {
+			return nil, fmt.Errorf("未知命令: %s", opts.Command)
+		}
+	}
+
+	fs := flag.NewFlagSet("chromego", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.BoolVar(&opts.Silent, "silent", false, "静默模式")
+	fs.BoolVar(&opts.Silent, "yes", false, "静默模式（同 --silent）")
+	fs.BoolVar(&opts.Silent, "y", false, "静默模式（同 --silent）")
+	fs.BoolVar(&opts.DryRun, "dry-run", false, "只输出更新计划")
+	fs.BoolVar(&opts.JSON, "json", false, "以 JSON 输出更新计划")
+	fs.BoolVar(&opts.Force, "force", false, "忽略维护窗口立即安装")
+	fs.StringVar(&opts.From, "from", "", "本地安装包路径")
+	fs.StringVar(&opts.SHA256, "sha256", "", "安装包的 SHA256")
+	fs.StringVar(&opts.Listen, "listen", DefaultServeAddr, "缓存服务监听地址")
+	fs.StringVar(&opts.Mirror.Out, "out", "", "离线镜像输出目录")
+	channels := fs.String("channels", "", "离线镜像包含的通道，逗号分隔")
+	arches := fs.String("arch", "x64", "离线镜像包含的架构，逗号分隔")
+	fs.StringVar(&opts.Mirror.ChromePlusTag, "chrome-plus-tag", "", "离线镜像使用的 Chrome++ 版本")
+	fs.StringVar(&opts.Key, "key", "", "签名私钥文件")
+	fs.BoolVar(&opts.Keygen, "keygen", false, "生成签名密钥对")
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+	// 子命令也可以写在选项之后：--silent update
+	if opts.Command == "" && fs.NArg() > 0 {
+		opts.Command = fs.Arg(0)
+		if !isCommand(opts.Command) {
+			return nil, fmt.Errorf("未知命令: %s", opts.Command)
+		}
+		if err := fs.Parse(fs.Args()[1:]); err != nil {
+			return nil, err
+		}
+	}
+	opts.Args = fs.Args()
+	if opts.Command == CommandInstall && opts.From == "" {
+		// 也可以直接跟在 install 后面：install <file>
+		if fs.NArg() == 0 {
+			return nil, fmt.Errorf("install 命令需要指定安装包: install --from <文件>")
+		}
+		opts.From = fs.Arg(0)
+		opts.Args = opts.Args[1:]
+	}
+	// 只有 sign 命令接受多个文件参数
+	if opts.Command != CommandSign && len(opts.Args) > 0 {
+		return nil, fmt.Errorf("多余的参数: %s", strings.Join(opts.Args, " "))
+	}
+	if opts.Command == CommandMirror {
+		if opts.Mirror.Out == "" {
+			return nil, fmt.Errorf("mirror 命令需要指定输出目录: mirror --out <目录>")
+		}
+		opts.Mirror.Channels = splitList(*channels)
+		opts.Mirror.Arches = splitList(*arches)
+	}
+	return opts, nil
+}
+
+// isCommand 判断是否为支持的子命令
+func isCommand(name string) bool {
+	switch name {
+	case CommandUpdate, CommandCheck, CommandInstall, CommandServe, CommandMirror, CommandSign:
+		return true
+	}
+	return false
+}
+
+// splitList 拆分逗号分隔的列表，忽略空项
+func splitList(value string) []string {
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// Console 是否为命令行调用，结果输出到控制台而非弹窗
+func (o *Options) Console() bool {
+	return o.DryRun || o.Command != ""
+}
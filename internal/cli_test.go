@@ -0,0 +1,50 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		want      Options
+		wantError string
+	}{
+		{name: "没有参数", args: nil},
+		{name: "默认流程带选项", args: []string{"--silent", "--force"}, want: Options{Silent: true, Force: true}},
+		{name: "子命令在选项之前", args: []string{"update", "--silent"}, want: Options{Command: CommandUpdate, Silent: true}},
+		{name: "子命令在选项之后", args: []string{"--silent", "update"}, want: Options{Command: CommandUpdate, Silent: true}},
+		{name: "子命令前后都有选项", args: []string{"-y", "check", "--dry-run", "--json"}, want: Options{Command: CommandCheck, Silent: true, DryRun: true, JSON: true}},
+		{name: "install 直接跟文件", args: []string{"install", "chrome.exe"}, want: Options{Command: CommandInstall, From: "chrome.exe"}},
+		{name: "选项之后的 install", args: []string{"--silent", "install", "--from", "chrome.exe"}, want: Options{Command: CommandInstall, Silent: true, From: "chrome.exe"}},
+		{name: "sign 接受多个文件", args: []string{"sign", "--key", "k", "data.json", "chrome_plus.json"}, want: Options{Command: CommandSign, Key: "k", Args: []string{"data.json", "chrome_plus.json"}}},
+		{name: "未知命令", args: []string{"upgrade"}, wantError: "未知命令: upgrade"},
+		{name: "选项之后的未知命令", args: []string{"--silent", "upgrade"}, wantError: "未知命令: upgrade"},
+		{name: "子命令之后多余的参数", args: []string{"--silent", "update", "now"}, wantError: "多余的参数: now"},
+		{name: "install 多余的参数", args: []string{"install", "--from", "chrome.exe", "chrome++.zip"}, wantError: "多余的参数: chrome++.zip"},
+		{name: "install 缺少安装包", args: []string{"install"}, wantError: "install 命令需要指定安装包"},
+		{name: "未知选项", args: []string{"update", "--quiet"}, wantError: "quiet"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts, err := ParseOptions(tt.args)
+			if tt.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+					t.Fatalf("err = %v, want %q", err, tt.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if opts.Command != tt.want.Command || opts.Silent != tt.want.Silent || opts.DryRun != tt.want.DryRun ||
+				opts.JSON != tt.want.JSON || opts.Force != tt.want.Force || opts.From != tt.want.From || opts.Key != tt.want.Key ||
+				strings.Join(opts.Args, " ") != strings.Join(tt.want.Args, " ") {
+				t.Fatalf("ParseOptions = %+v, want %+v", *opts, tt.want)
+			}
+		})
+	}
+}
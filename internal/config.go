@@ -2,8 +2,13 @@ package internal
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Config 程序配置
@@ -16,6 +21,98 @@ type Config struct {
 	KeepVersions             int    `json:"keep_versions"`               // 保留旧版本数量，默认 3
 	SkippedChromeVersion     string `json:"skipped_chrome_version"`      // 用户跳过的 Chrome 版本
 	SkippedChromePlusVersion string `json:"skipped_chrome_plus_version"` // 用户跳过的 Chrome++ 版本
+	Silent                   bool   `json:"silent"`                      // 静默模式：不弹窗，自动更新，输出写入日志
+	SilentCleanup            bool   `json:"silent_cleanup"`              // 静默模式下是否自动清理旧版本，默认 true
+	LogFile                  string `json:"log_file"`                    // 静默模式日志文件，默认 "chromego.log"
+
+	SnoozeHours           int        `json:"snooze_hours"`                       // "稍后提醒"的暂停小时数，默认 24
+	MaxSnoozeHours        int        `json:"max_snooze_hours"`                   // 暂停提醒的最长小时数，默认 168
+	ChromeSnoozeUntil     *time.Time `json:"chrome_snooze_until,omitempty"`      // Chrome 更新提醒暂停至
+	ChromePlusSnoozeUntil *time.Time `json:"chrome_plus_snooze_until,omitempty"` // Chrome++ 更新提醒暂停至
+
+	Policy                 UpdatePolicy `json:"policy"`                               // 更新紧急程度策略
+	ChromeUpdateSeenAt     *time.Time   `json:"chrome_update_seen_at,omitempty"`      // 首次发现 Chrome 有未安装新版本的时间
+	ChromePlusUpdateSeenAt *time.Time   `json:"chrome_plus_update_seen_at,omitempty"` // 首次发现 Chrome++ 有未安装新版本的时间
+	ChromeVersionSeen      *SeenVersion `json:"chrome_version_seen,omitempty"`        // 最新 Chrome 版本及首次发现时间
+	VersionHistoryURL      string       `json:"version_history_url"`                  // 版本发布时间查询 API，默认 Google VersionHistory
+
+	MaintenanceWindows []MaintenanceWindow `json:"maintenance_windows"` // 维护窗口，为空表示随时可安装更新
+
+	CheckIntervalMinutes int    `json:"check_interval_minutes"` // 两次联网检查更新的最短间隔（分钟），默认 60，0 表示每次都检查
+	GitHubToken          string `json:"github_token"`           // GitHub Token，提高 API 请求次数上限，为空时读取环境变量
+
+	GitHubMirrors   []string `json:"github_mirrors"`    // GitHub 加速镜像，前缀或包含 {url}/{path} 的模板
+	GitHubMirrorAPI bool     `json:"github_mirror_api"` // GitHub 元数据请求直连失败时是否也通过镜像获取
+
+	URLRewrites []URLRewrite `json:"url_rewrites"` // Chrome 下载地址改写规则
+
+	HostAuth        []HostAuth `json:"host_auth"`        // 按主机配置的认证信息和附加请求头
+	CredentialsFile string     `json:"credentials_file"` // 凭据文件，默认 "credentials.json"
+
+	ChromeDataURL        string `json:"chrome_data_url"`         // Chrome 版本数据地址，可为 file:// 地址、本地路径或目录
+	ChromePlusReleaseURL string `json:"chrome_plus_release_url"` // Chrome++ Release 信息地址，可为 file:// 地址、本地路径或目录
+
+	TrustedKeys      []string `json:"trusted_keys"`      // 受信任的 ed25519 公钥（base64）
+	RequireSignature bool     `json:"require_signature"` // 自定义数据源的元数据必须带有受信任的签名
+
+	RollbackAction   string            `json:"rollback_action"`              // 数据源版本低于曾见过的最高版本时的处理: warn（默认）/refuse
+	MaxMetadataAge   string            `json:"max_metadata_age"`             // 元数据时间早于该时长时发出警告，如 "14d"，空表示不检查
+	HighestSeen      map[string]string `json:"highest_seen,omitempty"`       // 各通道见过的最高版本（自动管理）
+	MetadataSignedAt *time.Time        `json:"metadata_signed_at,omitempty"` // 见过的最新 Chrome 元数据签名时间（自动管理）
+
+	PolicyURL string `json:"policy_url"` // 团队策略地址，可为 http(s) 地址、本地路径或目录，空表示不使用
+	ReportURL string `json:"report_url"` // 检查或更新后上报状态的地址，空表示不上报
+
+	Webhooks         []WebhookTarget   `json:"webhooks"`                    // 新版本和更新结果的通知目标
+	NotifiedVersions map[string]string `json:"notified_versions,omitempty"` // 已发送新版本通知的版本（自动管理）
+
+	PeerDiscovery bool `json:"peer_discovery"` // 下载时从局域网节点获取安装包；serve 命令应答节点发现
+
+	local json.RawMessage // 被团队策略覆盖的配置项的本地值，保存配置时写回
+}
+
+// 回滚处理方式
+const (
+	RollbackWarn   = "warn"   // 警告后继续
+	RollbackRefuse = "refuse" // 拒绝本次更新
+)
+
+// SeenVersion 版本及首次发现时间
+type SeenVersion struct {
+	Version   string    `json:"version"`
+	FirstSeen time.Time `json:"first_seen"`
+}
+
+// UpdatePolicy 更新紧急程度策略，超过阈值时不允许跳过更新
+type UpdatePolicy struct {
+	ForceMajorBehind int    `json:"force_major_behind"` // Chrome 落后的大版本数达到该值时强制更新，0 表示不限制
+	NoSkipAfterDays  int    `json:"no_skip_after_days"` // 首次发现新版本超过该天数后强制更新，0 表示不限制
+	MinReleaseAge    string `json:"min_release_age"`    // Chrome 新版本发布满该时长后才提示更新，如 "3d"、"72h"，空表示不限制
+}
+
+// GetMinReleaseAge 解析 Chrome 新版本的最短发布时长，无效值视为不限制
+func (p UpdatePolicy) GetMinReleaseAge() time.Duration {
+	return parseAge(p.MinReleaseAge)
+}
+
+// parseAge 解析时长，支持 "d" 天数后缀，空值和无效值返回 0
+func parseAge(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if strings.HasSuffix(value, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+		if err != nil || days < 0 {
+			return 0
+		}
+		return time.Duration(days) * 24 * time.Hour
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
 }
 
 // DefaultConfig 返回默认配置
@@ -29,6 +126,12 @@ func DefaultConfig() *Config {
 		KeepVersions:             3,
 		SkippedChromeVersion:     "",
 		SkippedChromePlusVersion: "",
+		Silent:                   false,
+		SilentCleanup:            true,
+		LogFile:                  "chromego.log",
+		SnoozeHours:              24,
+		MaxSnoozeHours:           168,
+		CheckIntervalMinutes:     60,
 	}
 }
 
@@ -51,10 +154,128 @@ func (c *Config) GetKeepVersions() int {
 	return c.KeepVersions
 }
 
+// GetMaxSnooze 获取暂停提醒的最长时长
+func (c *Config) GetMaxSnooze() time.Duration {
+	if c.MaxSnoozeHours <= 0 {
+		return 168 * time.Hour
+	}
+	return time.Duration(c.MaxSnoozeHours) * time.Hour
+}
+
+// GetSnooze 获取"稍后提醒"的暂停时长（不超过最长时长）
+func (c *Config) GetSnooze() time.Duration {
+	snooze := 24 * time.Hour
+	if c.SnoozeHours > 0 {
+		snooze = time.Duration(c.SnoozeHours) * time.Hour
+	}
+	if max := c.GetMaxSnooze(); snooze > max {
+		return max
+	}
+	return snooze
+}
+
+// SnoozeUntil 获取组件更新提醒的暂停截止时间，未暂停时返回 nil
+func (c *Config) SnoozeUntil(component string) *time.Time {
+	if component == ComponentChromePlus {
+		return c.ChromePlusSnoozeUntil
+	}
+	return c.ChromeSnoozeUntil
+}
+
+// SetSnoozeUntil 设置组件更新提醒的暂停截止时间，nil 表示取消暂停
+func (c *Config) SetSnoozeUntil(component string, until *time.Time) {
+	if component == ComponentChromePlus {
+		c.ChromePlusSnoozeUntil = until
+	} else {
+		c.ChromeSnoozeUntil = until
+	}
+}
+
+// UpdateSeenAt 获取首次发现组件有未安装新版本的时间，未记录时返回 nil
+func (c *Config) UpdateSeenAt(component string) *time.Time {
+	if component == ComponentChromePlus {
+		return c.ChromePlusUpdateSeenAt
+	}
+	return c.ChromeUpdateSeenAt
+}
+
+// SetUpdateSeenAt 设置首次发现组件有未安装新版本的时间，nil 表示清除
+func (c *Config) SetUpdateSeenAt(component string, at *time.Time) {
+	if component == ComponentChromePlus {
+		c.ChromePlusUpdateSeenAt = at
+	} else {
+		c.ChromeUpdateSeenAt = at
+	}
+}
+
+// GetCheckInterval 获取两次联网检查更新的最短间隔
+func (c *Config) GetCheckInterval() time.Duration {
+	if c.CheckIntervalMinutes <= 0 {
+		return 0
+	}
+	return time.Duration(c.CheckIntervalMinutes) * time.Minute
+}
+
+// GetRollbackAction 获取回滚处理方式，无效值视为 warn
+func (c *Config) GetRollbackAction() string {
+	if strings.EqualFold(strings.TrimSpace(c.RollbackAction), RollbackRefuse) {
+		return RollbackRefuse
+	}
+	return RollbackWarn
+}
+
+// GetMaxMetadataAge 获取元数据的最长有效时长，0 表示不检查
+func (c *Config) GetMaxMetadataAge() time.Duration {
+	return parseAge(c.MaxMetadataAge)
+}
+
+// GetGitHubToken 获取 GitHub Token
+// 配置为空时依次读取环境变量 CHROMEGO_GITHUB_TOKEN 和 GITHUB_TOKEN
+func (c *Config) GetGitHubToken() string {
+	if token := strings.TrimSpace(c.GitHubToken); token != "" {
+		return token
+	}
+	for _, name := range []string{"CHROMEGO_GITHUB_TOKEN", "GITHUB_TOKEN"} {
+		if token := strings.TrimSpace(os.Getenv(name)); token != "" {
+			return token
+		}
+	}
+	return ""
+}
+
+// GetLogFile 获取日志文件的绝对路径
+func (c *Config) GetLogFile() string {
+	logFile := c.LogFile
+	if logFile == "" {
+		logFile = "chromego.log"
+	}
+	if filepath.IsAbs(logFile) {
+		return logFile
+	}
+	return filepath.Join(programDir(), logFile)
+}
+
+// GetCredentialsFile 获取凭据文件的绝对路径
+func (c *Config) GetCredentialsFile() string {
+	file := c.CredentialsFile
+	if file == "" {
+		file = "credentials.json"
+	}
+	if filepath.IsAbs(file) {
+		return file
+	}
+	return filepath.Join(programDir(), file)
+}
+
+// programDir 返回程序所在目录，配置、日志、缓存和 App 目录都位于此目录下（测试中替换为临时目录）
+var programDir = func() string {
+	exe, _ := os.Executable()
+	return filepath.Dir(exe)
+}
+
 // ConfigPath 返回配置文件路径
 func ConfigPath() string {
-	exe, _ := os.Executable()
-	return filepath.Join(filepath.Dir(exe), "config.json")
+	return filepath.Join(programDir(), "config.json")
 }
 
 // LoadConfig 加载配置文件
@@ -74,47 +295,165 @@ func LoadConfig() (*Config, error) {
 		return nil, err
 	}
 
-	var cfg Config
-	if err := json.Unmarshal(data, &cfg); err != nil {
+	// 以默认配置为基础，缺失的配置项保持默认值
+	cfg := DefaultConfig()
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, err
+	}
+	if err := ValidateMaintenanceWindows(cfg.MaintenanceWindows); err != nil {
 		return nil, err
 	}
 
-	return &cfg, nil
+	return cfg, nil
 }
 
-// Save 保存配置到文件
+// Save 保存配置到文件，被团队策略覆盖的配置项保存本地值
 func (c *Config) Save() error {
-	data, err := json.MarshalIndent(c, "", "  ")
+	saved := c
+	if c.local != nil {
+		restored, err := c.withLocalValues()
+		if err != nil {
+			return err
+		}
+		saved = restored
+	}
+	data, err := json.MarshalIndent(saved, "", "  ")
 	if err != nil {
 		return err
 	}
 	return os.WriteFile(ConfigPath(), data, 0644)
 }
 
+// enforcedAllowlist 团队策略可以强制的配置项
+// 只包含更新行为相关的配置项；数据源、镜像、公钥、签名要求、认证信息、上报和通知地址等
+// 决定信任和数据去向的配置项，以及版本号等自动管理的状态，只能在本地配置
+var enforcedAllowlist = map[string]bool{
+	"channel":                true,
+	"threads":                true,
+	"keep_versions":          true,
+	"silent":                 true,
+	"silent_cleanup":         true,
+	"snooze_hours":           true,
+	"max_snooze_hours":       true,
+	"policy":                 true,
+	"maintenance_windows":    true,
+	"check_interval_minutes": true,
+	"rollback_action":        true,
+	"max_metadata_age":       true,
+}
+
+// ApplyEnforced 用团队策略强制的配置项覆盖当前配置（格式与 config.json 相同），返回被覆盖的配置项
+// 包含不允许强制的配置项时返回错误且不修改配置；覆盖只在本次运行中生效，保存配置时这些配置项仍写入本地值
+func (c *Config) ApplyEnforced(enforced json.RawMessage) ([]string, error) {
+	if len(enforced) == 0 {
+		return nil, nil
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(enforced, &fields); err != nil {
+		return nil, err
+	}
+	var rejected []string
+	for key := range fields {
+		if !enforcedAllowlist[key] {
+			rejected = append(rejected, key)
+		}
+	}
+	if len(rejected) > 0 {
+		sort.Strings(rejected)
+		return nil, fmt.Errorf("不允许强制的配置项: %s", strings.Join(rejected, ", "))
+	}
+
+	current, err := configFields(c)
+	if err != nil {
+		return nil, err
+	}
+	local := make(map[string]json.RawMessage)
+	if c.local != nil {
+		if err := json.Unmarshal(c.local, &local); err != nil {
+			return nil, err
+		}
+	}
+
+	var keys []string
+	for key := range fields {
+		if _, ok := local[key]; !ok {
+			local[key] = current[key]
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, err
+	}
+	if c.local, err = json.Marshal(local); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// withLocalValues 返回被覆盖的配置项恢复为本地值后的配置副本
+func (c *Config) withLocalValues() (*Config, error) {
+	fields, err := configFields(c)
+	if err != nil {
+		return nil, err
+	}
+	var local map[string]json.RawMessage
+	if err := json.Unmarshal(c.local, &local); err != nil {
+		return nil, err
+	}
+	for key, value := range local {
+		fields[key] = value
+	}
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	restored := &Config{}
+	if err := json.Unmarshal(data, restored); err != nil {
+		return nil, err
+	}
+	return restored, nil
+}
+
+// configFields 将配置转换为按配置项名称索引的 JSON
+func configFields(c *Config) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
 // GetChromePath 获取 Chrome 可执行文件的绝对路径
 func (c *Config) GetChromePath() string {
-	exe, _ := os.Executable()
-	baseDir := filepath.Dir(exe)
+	baseDir := programDir()
 	return filepath.Join(baseDir, c.ChromePath, "chrome.exe")
 }
 
 // GetChromePlusDllPath 获取 Chrome++ version.dll 的绝对路径
 func (c *Config) GetChromePlusDllPath() string {
-	exe, _ := os.Executable()
-	baseDir := filepath.Dir(exe)
+	baseDir := programDir()
 	return filepath.Join(baseDir, c.ChromePath, "version.dll")
 }
 
 // GetChromePlusIniPath 获取 Chrome++ INI 配置文件的绝对路径
 func (c *Config) GetChromePlusIniPath() string {
-	exe, _ := os.Executable()
-	baseDir := filepath.Dir(exe)
+	baseDir := programDir()
 	return filepath.Join(baseDir, c.ChromePath, "chrome++.ini")
 }
 
 // GetAppDir 获取 App 目录的绝对路径
 func (c *Config) GetAppDir() string {
-	exe, _ := os.Executable()
-	baseDir := filepath.Dir(exe)
+	baseDir := programDir()
 	return filepath.Join(baseDir, c.ChromePath)
 }
@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestApplyEnforced(t *testing.T) {
+	tests := []struct {
+		name     string
+		enforce  string
+		wantKeys []string
+		wantErr  string
+	}{
+		{name: "未强制"},
+		{
+			name:     "更新行为相关的配置项",
+			enforce:  `{"threads": 8, "policy": {"no_skip_after_days": 7}}`,
+			wantKeys: []string{"policy", "threads"},
+		},
+		{name: "受信任公钥", enforce: `{"trusted_keys": ["AAAA"]}`, wantErr: "trusted_keys"},
+		{name: "主机认证", enforce: `{"host_auth": [{"host": "example.com", "token": "x"}]}`, wantErr: "host_auth"},
+		{name: "签名要求", enforce: `{"require_signature": false}`, wantErr: "require_signature"},
+		{name: "数据源", enforce: `{"chrome_data_url": "https://example.com/data.json"}`, wantErr: "chrome_data_url"},
+		{name: "上报和通知地址", enforce: `{"report_url": "https://example.com", "webhooks": []}`, wantErr: "report_url, webhooks"},
+		{name: "自动管理的状态", enforce: `{"version": "1.0.0.0", "threads": 8}`, wantErr: "version"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.RequireSignature = true
+			cfg.TrustedKeys = []string{"local"}
+			before, _ := json.Marshal(cfg)
+
+			keys, err := cfg.ApplyEnforced(json.RawMessage(tt.enforce))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				if after, _ := json.Marshal(cfg); string(after) != string(before) {
+					t.Fatalf("被拒绝的策略不应修改配置: %s", after)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(keys, ",") != strings.Join(tt.wantKeys, ",") {
+				t.Fatalf("keys = %v, want %v", keys, tt.wantKeys)
+			}
+		})
+	}
+}
+
+func TestApplyEnforcedKeepsLocalValues(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Threads = 4
+	if _, err := cfg.ApplyEnforced(json.RawMessage(`{"threads": 8}`)); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Threads != 8 {
+		t.Fatalf("Threads = %d, want 8", cfg.Threads)
+	}
+	saved, err := cfg.withLocalValues()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Threads != 4 {
+		t.Fatalf("保存的 Threads = %d, want 本地值 4", saved.Threads)
+	}
+}
@@ -1,10 +1,13 @@
 package internal
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"strings"
 	"sync"
 	"sync/atomic"
 )
@@ -14,34 +17,50 @@ type DownloadProgress func(downloaded, total int64)
 
 // MultiSourceDownload 多源多线程下载文件
 // 将下载任务分配到多个 URL 源，每个源负责不同的分块
-func MultiSourceDownload(urls []string, destPath string, threads int, progress DownloadProgress) error {
+// size 为期望的文件大小（0 表示未知），报告的大小与之不符的源不使用，避免按不可信的大小预分配文件
+func MultiSourceDownload(urls []string, destPath string, size int64, threads int, progress DownloadProgress) error {
 	if len(urls) == 0 {
 		return fmt.Errorf("没有可用的下载链接")
 	}
 
 	// 只有一个 URL，使用普通多线程下载
 	if len(urls) == 1 {
-		return MultiThreadDownload(urls[0], destPath, threads, progress)
+		return MultiThreadDownload(urls[0], destPath, size, threads, progress)
 	}
 
-	// 获取文件大小（使用第一个 URL）
-	totalSize, supportsRange, err := getFileInfo(urls[0])
-	if err != nil {
-		// 第一个失败，尝试其他
-		for i := 1; i < len(urls); i++ {
-			totalSize, supportsRange, err = getFileInfo(urls[i])
-			if err == nil {
-				break
+	// 获取文件大小（使用第一个可用且大小相符的 URL）
+	var totalSize int64
+	var supportsRange bool
+	var err error
+	probeURL := urls[0]
+	rejected := make(map[string]bool)
+	for _, url := range urls {
+		totalSize, supportsRange, err = getFileInfo(url)
+		if err == nil {
+			if err = checkProbeSize(url, totalSize, size); err != nil {
+				rejected[url] = true
+				continue
 			}
+			probeURL = url
+			break
 		}
-		if err != nil {
-			return fmt.Errorf("无法获取文件信息: %w", err)
+	}
+	if err != nil {
+		return fmt.Errorf("无法获取文件信息: %w", err)
+	}
+	if len(rejected) > 0 {
+		var sources []string
+		for _, url := range urls {
+			if !rejected[url] {
+				sources = append(sources, url)
+			}
 		}
+		urls = sources
 	}
 
 	// 不支持 Range，降级为单线程
 	if !supportsRange || totalSize <= 0 {
-		return singleThreadDownload(urls[0], destPath, progress)
+		return singleThreadDownload(probeURL, destPath, size, progress)
 	}
 
 	// 创建目标文件
@@ -72,16 +91,21 @@ func MultiSourceDownload(urls []string, destPath string, threads int, progress D
 			end = totalSize - 1
 		}
 
-		// 轮询分配 URL
-		url := urls[i%urlCount]
+		// 轮询分配 URL，某个源失败时依次换用其他源重新下载该分块
+		first := i % urlCount
 
 		wg.Add(1)
-		go func(url string, start, end int64) {
+		go func(first int, start, end int64) {
 			defer wg.Done()
-			if err := downloadChunk(url, file, start, end, &downloadedBytes, progress, totalSize); err != nil {
-				errChan <- err
+			var err error
+			for j := 0; j < urlCount; j++ {
+				url := urls[(first+j)%urlCount]
+				if err = downloadChunk(url, file, start, end, &downloadedBytes, progress, totalSize); err == nil {
+					return
+				}
 			}
-		}(url, start, end)
+			errChan <- err
+		}(first, start, end)
 	}
 
 	wg.Wait()
@@ -97,9 +121,17 @@ func MultiSourceDownload(urls []string, destPath string, threads int, progress D
 	return nil
 }
 
+// checkProbeSize 检查源报告的文件大小，期望大小已知且源报告了不同的大小时返回错误
+func checkProbeSize(url string, probed, size int64) error {
+	if size > 0 && probed > 0 && probed != size {
+		return fmt.Errorf("%s: 文件大小 %d 与期望的 %d 不符", url, probed, size)
+	}
+	return nil
+}
+
 // getFileInfo 获取文件大小和是否支持 Range
 func getFileInfo(url string) (int64, bool, error) {
-	resp, err := http.Head(url)
+	resp, err := httpClient.Head(url)
 	if err != nil {
 		return 0, false, err
 	}
@@ -113,15 +145,18 @@ func getFileInfo(url string) (int64, bool, error) {
 	return resp.ContentLength, supportsRange, nil
 }
 
-// MultiThreadDownload 多线程下载文件（单源）
-func MultiThreadDownload(url, destPath string, threads int, progress DownloadProgress) error {
+// MultiThreadDownload 多线程下载文件（单源），size 为期望的文件大小（0 表示未知）
+func MultiThreadDownload(url, destPath string, size int64, threads int, progress DownloadProgress) error {
 	totalSize, supportsRange, err := getFileInfo(url)
 	if err != nil {
 		return err
 	}
+	if err := checkProbeSize(url, totalSize, size); err != nil {
+		return err
+	}
 
 	if totalSize <= 0 || !supportsRange {
-		return singleThreadDownload(url, destPath, progress)
+		return singleThreadDownload(url, destPath, size, progress)
 	}
 
 	// 创建目标文件
@@ -172,28 +207,40 @@ func MultiThreadDownload(url, destPath string, threads int, progress DownloadPro
 }
 
 // downloadChunk 下载文件的一部分
-func downloadChunk(url string, file *os.File, start, end int64, downloaded *int64, progress DownloadProgress, total int64) error {
+// 失败时撤销本分块已计入的进度，便于换用其他源重新下载
+func downloadChunk(url string, file *os.File, start, end int64, downloaded *int64, progress DownloadProgress, total int64) (err error) {
 	req, err := http.NewRequest("GET", url, nil)
 	if err != nil {
 		return err
 	}
 	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := httpClient.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusPartialContent {
+		return fmt.Errorf("%s: HTTP %d", url, resp.StatusCode)
+	}
+
 	buf := make([]byte, 32*1024) // 32KB buffer
 	offset := start
+	defer func() {
+		if err != nil {
+			atomic.AddInt64(downloaded, start-offset)
+		}
+	}()
 
 	for {
-		n, err := resp.Body.Read(buf)
+		n, readErr := resp.Body.Read(buf)
+		if offset+int64(n) > end+1 {
+			return fmt.Errorf("%s: 分块数据超出请求范围", url)
+		}
 		if n > 0 {
-			_, writeErr := file.WriteAt(buf[:n], offset)
-			if writeErr != nil {
-				return writeErr
+			if _, err := file.WriteAt(buf[:n], offset); err != nil {
+				return err
 			}
 			offset += int64(n)
 			newDownloaded := atomic.AddInt64(downloaded, int64(n))
@@ -201,25 +248,32 @@ func downloadChunk(url string, file *os.File, start, end int64, downloaded *int6
 				progress(newDownloaded, total)
 			}
 		}
-		if err == io.EOF {
+		if readErr == io.EOF {
 			break
 		}
-		if err != nil {
-			return err
+		if readErr != nil {
+			return readErr
 		}
 	}
 
+	if offset != end+1 {
+		return fmt.Errorf("%s: 分块数据不完整", url)
+	}
 	return nil
 }
 
-// singleThreadDownload 单线程下载（降级方案）
-func singleThreadDownload(url, destPath string, progress DownloadProgress) error {
-	resp, err := http.Get(url)
+// singleThreadDownload 单线程下载（降级方案），size 为期望的文件大小（0 表示未知），超出时中止
+func singleThreadDownload(url, destPath string, size int64, progress DownloadProgress) error {
+	resp, err := httpClient.Get(url)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: HTTP %d", url, resp.StatusCode)
+	}
+
 	file, err := os.Create(destPath)
 	if err != nil {
 		return err
@@ -227,6 +281,9 @@ func singleThreadDownload(url, destPath string, progress DownloadProgress) error
 	defer file.Close()
 
 	total := resp.ContentLength
+	if err := checkProbeSize(url, total, size); err != nil {
+		return err
+	}
 	var downloaded int64
 
 	buf := make([]byte, 32*1024)
@@ -238,6 +295,9 @@ func singleThreadDownload(url, destPath string, progress DownloadProgress) error
 				return writeErr
 			}
 			downloaded += int64(n)
+			if size > 0 && downloaded > size {
+				return fmt.Errorf("%s: 下载的数据超出期望的大小 %d", url, size)
+			}
 			if progress != nil {
 				progress(downloaded, total)
 			}
@@ -253,6 +313,58 @@ func singleThreadDownload(url, destPath string, progress DownloadProgress) error
 	return nil
 }
 
+// DownloadVerified 下载到 .part 临时文件，校验大小和 SHA256 后再改名为目标文件，避免留下不完整的文件
+func DownloadVerified(urls []string, destPath string, size int64, sha256Hex string, threads int, progress DownloadProgress) error {
+	partFile := destPath + ".part"
+	if err := MultiSourceDownload(urls, partFile, size, threads, progress); err != nil {
+		os.Remove(partFile)
+		return err
+	}
+	if err := VerifyFile(partFile, size, sha256Hex); err != nil {
+		os.Remove(partFile)
+		return err
+	}
+	return os.Rename(partFile, destPath)
+}
+
+// VerifyFile 校验文件大小和 SHA256，size 为 0 或 sha256Hex 为空时跳过对应校验
+func VerifyFile(path string, size int64, sha256Hex string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if size > 0 && info.Size() != size {
+		return fmt.Errorf("文件大小不匹配: 期望 %d，实际 %d", size, info.Size())
+	}
+	if sha256Hex == "" {
+		return nil
+	}
+
+	actual, err := fileSHA256(path)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(actual, sha256Hex) {
+		return fmt.Errorf("SHA256 校验失败: 期望 %s，实际 %s", sha256Hex, actual)
+	}
+	return nil
+}
+
+// fileSHA256 计算文件的 SHA256（小写十六进制）
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
 // FormatBytes 格式化字节数
 func FormatBytes(bytes int64) string {
 	const (
@@ -0,0 +1,159 @@
+package internal
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// newDownloadServer 返回提供 content 的服务，ranges 为 false 时不支持 Range，status 非 0 时 GET 返回该状态码
+func newDownloadServer(t *testing.T, content string, ranges bool, status int) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			if ranges {
+				w.Header().Set("Accept-Ranges", "bytes")
+			}
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			return
+		}
+		if status != 0 {
+			w.WriteHeader(status)
+			w.Write([]byte("error page"))
+			return
+		}
+		if ranges {
+			http.ServeContent(w, r, "file", time.Time{}, strings.NewReader(content))
+			return
+		}
+		w.Write([]byte(content))
+	}))
+	t.Cleanup(server.Close)
+	return server.URL + "/file"
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestDownloadVerified(t *testing.T) {
+	content := strings.Repeat("chrome installer ", 1000)
+
+	tests := []struct {
+		name    string
+		ranges  bool
+		status  int
+		sha256  string
+		wantErr string
+	}{
+		{name: "多线程", ranges: true, sha256: sha256Hex(content)},
+		{name: "单线程", sha256: sha256Hex(content)},
+		{name: "单线程服务器错误", status: http.StatusInternalServerError, sha256: sha256Hex(content), wantErr: "HTTP 500"},
+		{name: "SHA256 不匹配", ranges: true, sha256: sha256Hex("other"), wantErr: "SHA256 校验失败"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url := newDownloadServer(t, content, tt.ranges, tt.status)
+			dest := filepath.Join(t.TempDir(), "chrome_installer.exe")
+
+			err := DownloadVerified([]string{url}, dest, int64(len(content)), tt.sha256, 4, nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				for _, path := range []string{dest, dest + ".part"} {
+					if _, err := os.Stat(path); !os.IsNotExist(err) {
+						t.Fatalf("失败后不应留下 %s", filepath.Base(path))
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := readTestFile(t, dest); got != content {
+				t.Fatalf("下载内容不一致（%d 字节）", len(got))
+			}
+		})
+	}
+}
+
+// newOversizedPeer 返回报告错误大小的节点，HEAD 声称文件有 1 TiB，GET 返回无穷无尽的数据，gets 记录 GET 请求数
+func newOversizedPeer(t *testing.T, ranges bool) (string, *int64) {
+	t.Helper()
+	var gets int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			if ranges {
+				w.Header().Set("Accept-Ranges", "bytes")
+			}
+			w.Header().Set("Content-Length", strconv.FormatInt(1<<40, 10))
+			return
+		}
+		atomic.AddInt64(&gets, 1)
+		chunk := []byte(strings.Repeat("x", 32*1024))
+		for {
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server.URL + "/peer/file", &gets
+}
+
+func TestDownloadVerifiedRejectsWrongSize(t *testing.T) {
+	content := strings.Repeat("chrome installer ", 1000)
+	size := int64(len(content))
+
+	t.Run("大小不符的源不参与下载", func(t *testing.T) {
+		peer, gets := newOversizedPeer(t, true)
+		upstream := newDownloadServer(t, content, true, 0)
+		dest := filepath.Join(t.TempDir(), "chrome.exe")
+
+		if err := DownloadVerified([]string{peer, upstream}, dest, size, sha256Hex(content), 4, nil); err != nil {
+			t.Fatal(err)
+		}
+		if got := readTestFile(t, dest); got != content {
+			t.Fatal("下载内容不一致")
+		}
+		if n := atomic.LoadInt64(gets); n != 0 {
+			t.Fatalf("大小不符的节点收到 %d 个下载请求", n)
+		}
+	})
+
+	t.Run("只有大小不符的源", func(t *testing.T) {
+		peer, gets := newOversizedPeer(t, true)
+		dest := filepath.Join(t.TempDir(), "chrome.exe")
+		err := DownloadVerified([]string{peer}, dest, size, sha256Hex(content), 4, nil)
+		if err == nil || !strings.Contains(err.Error(), "与期望的") {
+			t.Fatalf("err = %v, want 文件大小不符", err)
+		}
+		if n := atomic.LoadInt64(gets); n != 0 || fileExists(dest+".part") {
+			t.Fatalf("不应下载或预分配文件, GET = %d", n)
+		}
+	})
+
+	t.Run("单线程下载超出期望大小时中止", func(t *testing.T) {
+		// GET 响应没有 Content-Length，只能在读取过程中限制
+		peer, _ := newOversizedPeer(t, false)
+		dest := filepath.Join(t.TempDir(), "chrome.exe")
+		err := singleThreadDownload(peer, dest, size, nil)
+		if err == nil || !strings.Contains(err.Error(), "超出期望的大小") {
+			t.Fatalf("err = %v, want 超出期望的大小", err)
+		}
+		if info, statErr := os.Stat(dest); statErr != nil || info.Size() > size+32*1024 {
+			t.Fatalf("写入的数据应受期望大小限制: %v %v", info, statErr)
+		}
+	})
+}
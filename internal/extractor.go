@@ -1,6 +1,10 @@
 package internal
 
 import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
 	"fmt"
 	"io"
 	"os"
@@ -9,18 +13,93 @@ import (
 	"strings"
 
 	"github.com/bodgit/sevenzip"
+	"github.com/ulikunitz/xz"
 )
 
-// Extract7z 使用纯 Go 解压 7z 文件到指定目录
-// 如果纯 Go 库失败，会尝试使用外部 7z 程序
-func Extract7z(archivePath, destDir string) error {
-	// 确保目标目录存在
+// 支持的压缩格式
+const (
+	Format7z    = "7z"
+	FormatZip   = "zip"
+	FormatTarGz = "tar.gz"
+	FormatTarXz = "tar.xz"
+)
+
+// archiveExtensions 支持的压缩包扩展名，按选择 Release 资源时的优先顺序排列
+var archiveExtensions = []string{".7z", ".zip", ".tar.xz", ".txz", ".tar.gz", ".tgz"}
+
+// ExtractProgress 解压进度回调，done 和 total 为已处理和总共的字节数
+type ExtractProgress func(done, total int64)
+
+// archiveExt 返回文件名中支持的压缩包扩展名（小写），不支持时返回空字符串
+func archiveExt(name string) string {
+	lower := strings.ToLower(name)
+	for _, ext := range archiveExtensions {
+		if strings.HasSuffix(lower, ext) {
+			return ext
+		}
+	}
+	return ""
+}
+
+// 压缩格式的文件头魔数
+var (
+	magic7z       = []byte{'7', 'z', 0xBC, 0xAF, 0x27, 0x1C}
+	magicZip      = []byte{'P', 'K', 0x03, 0x04}
+	magicZipEmpty = []byte{'P', 'K', 0x05, 0x06} // 空 zip 只有目录结束记录
+	magicGzip     = []byte{0x1F, 0x8B}
+	magicXz       = []byte{0xFD, '7', 'z', 'X', 'Z', 0x00}
+)
+
+// DetectArchiveFormat 根据文件头（而不是扩展名）判断压缩格式
+// PE 文件按 7z 自解压程序处理
+func DetectArchiveFormat(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	header := make([]byte, len(magic7z))
+	n, _ := io.ReadFull(file, header)
+	file.Close()
+	header = header[:n]
+
+	switch {
+	case bytes.HasPrefix(header, magic7z), bytes.HasPrefix(header, magicPE):
+		return Format7z, nil
+	case bytes.HasPrefix(header, magicZip), bytes.HasPrefix(header, magicZipEmpty):
+		return FormatZip, nil
+	case bytes.HasPrefix(header, magicGzip):
+		return FormatTarGz, nil
+	case bytes.HasPrefix(header, magicXz):
+		return FormatTarXz, nil
+	default:
+		return "", fmt.Errorf("无法识别的压缩格式")
+	}
+}
+
+// ExtractArchive 根据文件内容识别格式（7z、zip、tar.gz、tar.xz）并解压到指定目录
+func ExtractArchive(archivePath, destDir string, progress ExtractProgress) error {
+	format, err := DetectArchiveFormat(archivePath)
+	if err != nil {
+		return err
+	}
 	if err := os.MkdirAll(destDir, 0755); err != nil {
 		return fmt.Errorf("创建目标目录失败: %w", err)
 	}
 
+	switch format {
+	case Format7z:
+		return extract7z(archivePath, destDir, progress)
+	case FormatZip:
+		return extractZip(archivePath, destDir, progress)
+	default:
+		return extractTar(archivePath, destDir, format, progress)
+	}
+}
+
+// extract7z 解压 7z 文件，纯 Go 库失败时使用外部 7z 程序
+func extract7z(archivePath, destDir string, progress ExtractProgress) error {
 	// 首先尝试纯 Go 解压
-	err := extract7zPureGo(archivePath, destDir)
+	err := extract7zPureGo(archivePath, destDir, progress)
 	if err == nil {
 		return nil
 	}
@@ -41,21 +120,25 @@ func Extract7z(archivePath, destDir string) error {
 	return fmt.Errorf("解压失败: %v", extErr)
 }
 
-// extract7zPureGo 使用纯 Go 库解压
-func extract7zPureGo(archivePath, destDir string) error {
+// extract7zPureGo 使用纯 Go 库解压，进度按解压后的大小计算
+func extract7zPureGo(archivePath, destDir string, progress ExtractProgress) error {
 	r, err := sevenzip.OpenReader(archivePath)
 	if err != nil {
 		return fmt.Errorf("打开压缩包失败: %w", err)
 	}
 	defer r.Close()
 
+	counter := &progressCounter{fn: progress}
+	for _, f := range r.File {
+		counter.total += int64(f.UncompressedSize)
+	}
+
 	for _, f := range r.File {
 		// 清理路径，防止路径遍历攻击
-		name := filepath.Clean(f.Name)
-		if strings.HasPrefix(name, "..") {
+		destPath, ok := safeJoin(destDir, f.Name)
+		if !ok {
 			continue
 		}
-		destPath := filepath.Join(destDir, name)
 
 		// 处理目录
 		if f.FileInfo().IsDir() {
@@ -65,13 +148,8 @@ func extract7zPureGo(archivePath, destDir string) error {
 			continue
 		}
 
-		// 确保父目录存在
-		if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
-			return fmt.Errorf("创建父目录失败: %w", err)
-		}
-
 		// 解压文件
-		if err := extractFilePureGo(f, destPath); err != nil {
+		if err := extractFilePureGo(f, destPath, counter); err != nil {
 			return err
 		}
 	}
@@ -80,26 +158,223 @@ func extract7zPureGo(archivePath, destDir string) error {
 }
 
 // extractFilePureGo 解压单个文件
-func extractFilePureGo(f *sevenzip.File, destPath string) error {
+func extractFilePureGo(f *sevenzip.File, destPath string, counter *progressCounter) error {
 	rc, err := f.Open()
 	if err != nil {
 		return fmt.Errorf("打开压缩文件失败: %w", err)
 	}
 	defer rc.Close()
 
+	return writeEntry(destPath, io.TeeReader(rc, counter))
+}
+
+// extractZip 解压 zip 文件，进度按解压后的大小计算
+func extractZip(archivePath, destDir string, progress ExtractProgress) error {
+	r, err := zip.OpenReader(archivePath)
+	if err != nil {
+		return fmt.Errorf("打开压缩包失败: %w", err)
+	}
+	defer r.Close()
+
+	counter := &progressCounter{fn: progress}
+	for _, f := range r.File {
+		counter.total += int64(f.UncompressedSize64)
+	}
+
+	for _, f := range r.File {
+		destPath, ok := safeJoin(destDir, f.Name)
+		if !ok {
+			continue
+		}
+		mode := f.Mode()
+		if mode.IsDir() {
+			if err := os.MkdirAll(destPath, 0755); err != nil {
+				return fmt.Errorf("创建目录失败: %w", err)
+			}
+			continue
+		}
+		if !mode.IsRegular() {
+			// 符号链接等特殊文件可能指向目标目录之外，跳过
+			continue
+		}
+
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("打开压缩文件失败: %w", err)
+		}
+		err = writeEntry(destPath, io.TeeReader(rc, counter))
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractTar 解压 tar.gz / tar.xz 文件
+// 压缩流只能顺序读取，解压后的总大小未知，进度按已读取的压缩包大小计算
+func extractTar(archivePath, destDir, format string, progress ExtractProgress) error {
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return fmt.Errorf("打开压缩包失败: %w", err)
+	}
+	defer file.Close()
+
+	counter := &progressCounter{fn: progress}
+	if info, err := file.Stat(); err == nil {
+		counter.total = info.Size()
+	}
+	tr, err := tarStream(io.TeeReader(file, counter), format)
+	if err != nil {
+		return err
+	}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			// tar 结尾的填充可能未被读取，完成时补齐进度
+			if progress != nil && counter.total > 0 {
+				progress(counter.total, counter.total)
+			}
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("读取压缩包失败: %w", err)
+		}
+
+		destPath, ok := safeJoin(destDir, header.Name)
+		if !ok {
+			continue
+		}
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(destPath, 0755); err != nil {
+				return fmt.Errorf("创建目录失败: %w", err)
+			}
+		case tar.TypeReg:
+			if err := writeEntry(destPath, tr); err != nil {
+				return err
+			}
+		default:
+			// 符号链接、硬链接等特殊文件可能指向目标目录之外，跳过
+		}
+	}
+}
+
+// listArchive 返回压缩包中的文件名（统一为 / 分隔）
+func listArchive(archivePath string) ([]string, error) {
+	format, err := DetectArchiveFormat(archivePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	switch format {
+	case Format7z:
+		r, err := sevenzip.OpenReader(archivePath)
+		if err != nil {
+			return nil, fmt.Errorf("打开压缩包失败: %w", err)
+		}
+		defer r.Close()
+		for _, f := range r.File {
+			names = append(names, filepath.ToSlash(f.Name))
+		}
+	case FormatZip:
+		r, err := zip.OpenReader(archivePath)
+		if err != nil {
+			return nil, fmt.Errorf("打开压缩包失败: %w", err)
+		}
+		defer r.Close()
+		for _, f := range r.File {
+			names = append(names, f.Name)
+		}
+	default:
+		file, err := os.Open(archivePath)
+		if err != nil {
+			return nil, fmt.Errorf("打开压缩包失败: %w", err)
+		}
+		defer file.Close()
+		tr, err := tarStream(file, format)
+		if err != nil {
+			return nil, err
+		}
+		for {
+			header, err := tr.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("读取压缩包失败: %w", err)
+			}
+			names = append(names, header.Name)
+		}
+	}
+	return names, nil
+}
+
+// tarStream 解压 tar.gz / tar.xz 的压缩流，返回 tar 读取器
+func tarStream(r io.Reader, format string) (*tar.Reader, error) {
+	var stream io.Reader
+	var err error
+	switch format {
+	case FormatTarGz:
+		stream, err = gzip.NewReader(r)
+	case FormatTarXz:
+		stream, err = xz.NewReader(r)
+	default:
+		return nil, fmt.Errorf("不支持的压缩格式: %s", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("打开压缩包失败: %w", err)
+	}
+	return tar.NewReader(stream), nil
+}
+
+// safeJoin 返回压缩包内文件的解压路径
+// 清理路径，绝对路径或跳出目标目录的路径返回 false，防止路径遍历攻击
+func safeJoin(destDir, name string) (string, bool) {
+	name = filepath.Clean(filepath.FromSlash(strings.ReplaceAll(name, "\\", "/")))
+	if name == "." || filepath.IsAbs(name) || filepath.VolumeName(name) != "" ||
+		name == ".." || strings.HasPrefix(name, ".."+string(filepath.Separator)) ||
+		strings.HasPrefix(name, string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.Join(destDir, name), true
+}
+
+// writeEntry 将压缩包中的文件写入目标路径，自动创建父目录
+func writeEntry(destPath string, r io.Reader) error {
+	// 确保父目录存在
+	if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+		return fmt.Errorf("创建父目录失败: %w", err)
+	}
+
 	outFile, err := os.Create(destPath)
 	if err != nil {
 		return fmt.Errorf("创建文件失败: %w", err)
 	}
 	defer outFile.Close()
 
-	if _, err := io.Copy(outFile, rc); err != nil {
+	if _, err := io.Copy(outFile, r); err != nil {
 		return fmt.Errorf("写入文件失败: %w", err)
 	}
 
 	return nil
 }
 
+// progressCounter 统计已处理的字节数并回调解压进度
+type progressCounter struct {
+	fn          ExtractProgress
+	done, total int64
+}
+
+func (p *progressCounter) Write(b []byte) (int, error) {
+	p.done += int64(len(b))
+	if p.fn != nil {
+		p.fn(p.done, p.total)
+	}
+	return len(b), nil
+}
+
 // extract7zExternal 使用外部 7z 程序解压
 func extract7zExternal(archivePath, destDir string) error {
 	// 尝试多个可能的 7z 路径
@@ -0,0 +1,203 @@
+package internal
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ulikunitz/xz"
+)
+
+// archiveEntry 测试压缩包中的条目，link 非空时为指向 link 的符号链接
+type archiveEntry struct {
+	name string
+	body string
+	dir  bool
+	link string
+}
+
+// writeArchive 按格式将条目写入压缩包
+func writeArchive(t *testing.T, path, format string, entries []archiveEntry) {
+	t.Helper()
+	out, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+
+	if format == FormatZip {
+		zw := zip.NewWriter(out)
+		for _, e := range entries {
+			header := &zip.FileHeader{Name: e.name, Method: zip.Deflate}
+			body := e.body
+			switch {
+			case e.dir:
+				header.SetMode(os.ModeDir | 0755)
+			case e.link != "":
+				header.SetMode(os.ModeSymlink | 0777)
+				body = e.link
+			default:
+				header.SetMode(0644)
+			}
+			w, err := zw.CreateHeader(header)
+			if err != nil {
+				t.Fatal(err)
+			}
+			io.WriteString(w, body)
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	var stream io.WriteCloser
+	if format == FormatTarGz {
+		stream = gzip.NewWriter(out)
+	} else {
+		if stream, err = xz.NewWriter(out); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tw := tar.NewWriter(stream)
+	for _, e := range entries {
+		header := &tar.Header{Name: e.name, Mode: 0644, Size: int64(len(e.body)), Typeflag: tar.TypeReg}
+		switch {
+		case e.dir:
+			header = &tar.Header{Name: e.name, Mode: 0755, Typeflag: tar.TypeDir}
+		case e.link != "":
+			header = &tar.Header{Name: e.name, Mode: 0777, Typeflag: tar.TypeSymlink, Linkname: e.link}
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(tw, e.body)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := stream.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestExtractArchive(t *testing.T) {
+	for _, format := range []string{FormatZip, FormatTarGz, FormatTarXz} {
+		t.Run(format, func(t *testing.T) {
+			dir := t.TempDir()
+			archive := filepath.Join(dir, "package.bin")
+			writeArchive(t, archive, format, []archiveEntry{
+				{name: "Chrome-bin/", dir: true},
+				{name: "Chrome-bin/chrome.exe", body: "chrome"},
+				{name: "Chrome-bin/121.0.1.0/chrome.dll", body: "dll"},
+				{name: "Chrome-bin/empty/", dir: true},
+			})
+
+			if got, err := DetectArchiveFormat(archive); err != nil || got != format {
+				t.Fatalf("DetectArchiveFormat = %q, %v, want %q", got, err, format)
+			}
+
+			dest := filepath.Join(dir, "dest")
+			var done, total int64
+			err := ExtractArchive(archive, dest, func(d, tt int64) { done, total = d, tt })
+			if err != nil {
+				t.Fatal(err)
+			}
+			for name, want := range map[string]string{
+				"Chrome-bin/chrome.exe":           "chrome",
+				"Chrome-bin/121.0.1.0/chrome.dll": "dll",
+			} {
+				if got := readTestFile(t, filepath.Join(dest, filepath.FromSlash(name))); got != want {
+					t.Fatalf("%s = %q, want %q", name, got, want)
+				}
+			}
+			if info, err := os.Stat(filepath.Join(dest, "Chrome-bin", "empty")); err != nil || !info.IsDir() {
+				t.Fatalf("空目录未创建: %v", err)
+			}
+			if total == 0 || done != total {
+				t.Fatalf("进度 = %d/%d, want 完成", done, total)
+			}
+		})
+	}
+}
+
+func TestExtractArchiveRejectsUnsafeEntries(t *testing.T) {
+	for _, format := range []string{FormatZip, FormatTarGz, FormatTarXz} {
+		t.Run(format, func(t *testing.T) {
+			dir := t.TempDir()
+			outside := filepath.Join(dir, "outside")
+			if err := os.MkdirAll(outside, 0755); err != nil {
+				t.Fatal(err)
+			}
+			archive := filepath.Join(dir, "package.bin")
+			writeArchive(t, archive, format, []archiveEntry{
+				{name: "../outside/parent.txt", body: "parent"},
+				{name: "a/../../outside/nested.txt", body: "nested"},
+				{name: filepath.ToSlash(filepath.Join(outside, "absolute.txt")), body: "absolute"},
+				{name: "link", link: outside},
+				{name: "link/through-link.txt", body: "through link"},
+				{name: "ok.txt", body: "ok"},
+			})
+
+			dest := filepath.Join(dir, "dest")
+			if err := ExtractArchive(archive, dest, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			if entries, _ := os.ReadDir(outside); len(entries) != 0 {
+				t.Fatalf("解压写到了目标目录之外: %v", entries)
+			}
+			if info, err := os.Lstat(filepath.Join(dest, "link")); err == nil && info.Mode()&os.ModeSymlink != 0 {
+				t.Fatal("不应创建符号链接")
+			}
+			if got := readTestFile(t, filepath.Join(dest, "ok.txt")); got != "ok" {
+				t.Fatalf("ok.txt = %q", got)
+			}
+		})
+	}
+}
+
+func TestDetectArchiveFormat(t *testing.T) {
+	dir := t.TempDir()
+
+	// 空 zip 只有目录结束记录
+	empty := filepath.Join(dir, "empty.zip")
+	writeArchive(t, empty, FormatZip, nil)
+	if got, err := DetectArchiveFormat(empty); err != nil || got != FormatZip {
+		t.Fatalf("空 zip: DetectArchiveFormat = %q, %v", got, err)
+	}
+	if err := ExtractArchive(empty, filepath.Join(dir, "empty"), nil); err != nil {
+		t.Fatalf("解压空 zip 失败: %v", err)
+	}
+
+	tests := map[string]struct {
+		content string
+		want    string
+	}{
+		"7z":   {content: "7z\xbc\xaf\x27\x1c\x00\x04", want: Format7z},
+		"PE":   {content: "MZ\x90\x00", want: Format7z},
+		"文本":   {content: "hello world"},
+		"空文件":  {content: ""},
+		"截断魔数": {content: "P"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, "file.bin")
+			writeTestFile(t, path, tt.content)
+			got, err := DetectArchiveFormat(path)
+			if tt.want == "" {
+				if err == nil {
+					t.Fatalf("DetectArchiveFormat = %q, want 错误", got)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Fatalf("DetectArchiveFormat = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package internal
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+)
+
+const (
+	// Chrome++ GitHub 仓库
+	chromePlusRepo = "Bush2021/chrome_plus"
+	// Chrome++ GitHub API
+	chromePlusAPI = "https://api.github.com/repos/" + chromePlusRepo + "/releases/latest"
+	// Chrome++ 指定版本的 GitHub API，后接 tag
+	chromePlusTagAPI = "https://api.github.com/repos/" + chromePlusRepo + "/releases/tags/"
+	// Chrome++ Release 页面，API 不可用时使用
+	chromePlusReleasesURL = "https://github.com/" + chromePlusRepo + "/releases"
+)
+
+// releaseDownloadRegex Release 资源页面中的下载链接
+var releaseDownloadRegex = regexp.MustCompile(`href="(/[^"]+/releases/download/[^"]+)"`)
+
+// atomFeed GitHub Releases Atom 订阅结构
+type atomFeed struct {
+	Entries []struct {
+		Link struct {
+			Href string `xml:"href,attr"`
+		} `xml:"link"`
+	} `xml:"entry"`
+}
+
+// fetchChromePlusRelease 获取 Chrome++ 最新 Release（指定了 ChromePlusTag 时为该版本），同时返回解析资源相对地址的基准地址
+// 配置了自定义地址时只使用该地址；否则优先使用 GitHub API（配置了 Token 时带认证），失败时依次退回 Atom 订阅和 releases/latest 跳转
+func (m *MetadataClient) fetchChromePlusRelease() (*GitHubRelease, string, error) {
+	if m.ChromePlusReleaseURL != "" {
+		source := metadataSource(m.ChromePlusReleaseURL, chromePlusRelFile)
+		release, err := m.fetchRelease(source, m.RequireSignature)
+		return release, source, err
+	}
+
+	api := chromePlusAPI
+	if m.ChromePlusTag != "" {
+		api = chromePlusTagAPI + m.ChromePlusTag
+	}
+	release, apiErr := m.fetchRelease(api, false)
+	if apiErr == nil {
+		return release, api, nil
+	}
+
+	tag := m.ChromePlusTag
+	var err error
+	if tag == "" {
+		tag, err = m.fetchLatestTagFromAtom(chromePlusReleasesURL + ".atom")
+		if err != nil {
+			tag, err = fetchLatestTagFromRedirect(chromePlusReleasesURL + "/latest")
+		}
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("GitHub API: %v; 备用方式: %v", apiErr, err)
+	}
+
+	assetsURL := chromePlusReleasesURL + "/expanded_assets/" + tag
+	assets, err := m.fetchReleaseAssets(assetsURL, tag)
+	if err != nil {
+		return nil, "", fmt.Errorf("GitHub API: %v; 获取 %s 的资源列表失败: %v", apiErr, tag, err)
+	}
+	return &GitHubRelease{TagName: tag, Assets: assets}, assetsURL, nil
+}
+
+// fetchRelease 获取 Release 信息（GitHub Release API 格式），Token 只发送给 GitHub API
+// signed 为 true 时要求并校验签名
+func (m *MetadataClient) fetchRelease(url string, signed bool) (*GitHubRelease, error) {
+	header := http.Header{}
+	header.Set("Accept", "application/vnd.github.v3+json")
+	header.Set("User-Agent", "ChromeGo-Updater")
+	if m.GitHubToken != "" && strings.HasPrefix(url, "https://api.github.com/") {
+		header.Set("Authorization", "Bearer "+m.GitHubToken)
+	}
+
+	body, err := m.fetchMetadata(url, header, signed)
+	if err != nil {
+		return nil, err
+	}
+	var release GitHubRelease
+	if err := json.Unmarshal(body, &release); err != nil {
+		return nil, err
+	}
+	if release.TagName == "" {
+		return nil, errors.New("Release 信息缺少 tag_name")
+	}
+	return &release, nil
+}
+
+// fetchLatestTagFromAtom 从 Releases Atom 订阅获取最新的 tag
+func (m *MetadataClient) fetchLatestTagFromAtom(url string) (string, error) {
+	body, err := m.fetchGitHub(url, nil)
+	if err != nil {
+		return "", err
+	}
+
+	var feed atomFeed
+	if err := xml.Unmarshal(body, &feed); err != nil {
+		return "", err
+	}
+	for _, entry := range feed.Entries {
+		if tag := tagFromReleaseURL(entry.Link.Href); tag != "" {
+			return tag, nil
+		}
+	}
+	return "", errors.New("Atom 订阅中没有 Release")
+}
+
+// fetchLatestTagFromRedirect 从 releases/latest 的跳转地址获取最新的 tag
+func fetchLatestTagFromRedirect(url string) (string, error) {
+	client := &http.Client{
+		Transport: httpClient.Transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Head(url)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+
+	if tag := tagFromReleaseURL(resp.Header.Get("Location")); tag != "" {
+		return tag, nil
+	}
+	return "", fmt.Errorf("HTTP %d，未跳转到 Release 页面", resp.StatusCode)
+}
+
+// fetchReleaseAssets 从 Release 资源页面解析下载链接
+func (m *MetadataClient) fetchReleaseAssets(url, tag string) ([]Asset, error) {
+	body, err := m.fetchGitHub(url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var assets []Asset
+	for _, match := range releaseDownloadRegex.FindAllStringSubmatch(string(body), -1) {
+		href := match[1]
+		if !strings.Contains(href, "/download/"+tag+"/") {
+			continue
+		}
+		assets = append(assets, Asset{
+			Name:               path.Base(href),
+			BrowserDownloadURL: "https://github.com" + href,
+		})
+	}
+	if len(assets) == 0 {
+		return nil, errors.New("没有找到下载链接")
+	}
+	return assets, nil
+}
+
+// tagFromReleaseURL 从 .../releases/tag/<tag> 形式的地址中提取 tag
+func tagFromReleaseURL(url string) string {
+	idx := strings.LastIndex(url, "/releases/tag/")
+	if idx < 0 {
+		return ""
+	}
+	return strings.Trim(url[idx+len("/releases/tag/"):], "/")
+}
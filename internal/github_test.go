@@ -0,0 +1,157 @@
+package internal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// routeHTTP 将共用客户端的所有请求转发到替身服务，原主机名保留在 Host 请求头中
+func routeHTTP(t *testing.T, handler http.HandlerFunc) {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	target, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oldBase := defaultTransport.base
+	defaultTransport.base = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		req = req.Clone(req.Context())
+		req.Host = req.URL.Host
+		req.URL.Scheme, req.URL.Host = target.Scheme, target.Host
+		return oldBase.RoundTrip(req)
+	})
+	t.Cleanup(func() { defaultTransport.base = oldBase })
+}
+
+// roundTripFunc 函数形式的 http.RoundTripper
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// githubAtom 返回包含指定 tag 的 Releases Atom 订阅
+func githubAtom(tags ...string) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?><feed xmlns="http://www.w3.org/2005/Atom">`)
+	for _, tag := range tags {
+		b.WriteString(`<entry><link rel="alternate" type="text/html" href="https://github.com/` + chromePlusRepo + `/releases/tag/` + tag + `"/></entry>`)
+	}
+	b.WriteString(`</feed>`)
+	return b.String()
+}
+
+// githubExpandedAssets 返回指定 tag 的 Release 资源页面
+func githubExpandedAssets(tag string, names ...string) string {
+	var b strings.Builder
+	b.WriteString(`<ul>`)
+	for _, name := range names {
+		b.WriteString(`<li><a href="/` + chromePlusRepo + `/releases/download/` + tag + `/` + name + `" rel="nofollow">` + name + `</a></li>`)
+	}
+	b.WriteString(`<li><a href="/` + chromePlusRepo + `/archive/refs/tags/` + tag + `.zip" rel="nofollow">Source code</a></li></ul>`)
+	return b.String()
+}
+
+func TestChromePlusReleaseFallback(t *testing.T) {
+	repo := "/" + chromePlusRepo + "/releases"
+	asset := "Chrome++_v1.2.0_x64.7z"
+	assetURL := "https://github.com" + repo + "/download/v1.2.0/" + asset
+
+	// rateLimited GitHub API 被限流的响应
+	rateLimited := func(status int) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", "4102444800")
+			w.WriteHeader(status)
+		}
+	}
+
+	tests := []struct {
+		name      string
+		tag       string // ChromePlusTag
+		api       http.HandlerFunc
+		atom      http.HandlerFunc
+		latest    http.HandlerFunc
+		wantTag   string
+		wantError string
+	}{
+		{
+			name:    "API 返回 403 时使用 Atom 订阅",
+			api:     rateLimited(http.StatusForbidden),
+			atom:    func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(githubAtom("v1.2.0", "v1.1.0"))) },
+			wantTag: "v1.2.0",
+		},
+		{
+			name: "API 返回 429 且 Atom 订阅不可用时使用跳转地址",
+			api: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Retry-After", "60")
+				w.WriteHeader(http.StatusTooManyRequests)
+			},
+			atom: func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusInternalServerError) },
+			latest: func(w http.ResponseWriter, r *http.Request) {
+				http.Redirect(w, r, "https://github.com"+repo+"/tag/v1.2.0", http.StatusFound)
+			},
+			wantTag: "v1.2.0",
+		},
+		{
+			name:    "指定版本时直接获取资源页面",
+			tag:     "v1.2.0",
+			api:     rateLimited(http.StatusForbidden),
+			wantTag: "v1.2.0",
+		},
+		{
+			name:      "备用方式均失败",
+			api:       rateLimited(http.StatusTooManyRequests),
+			atom:      func(w http.ResponseWriter, r *http.Request) { w.Write([]byte(githubAtom())) },
+			latest:    func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNotFound) },
+			wantError: "备用方式",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notFound := func(w http.ResponseWriter, r *http.Request) { http.NotFound(w, r) }
+			routeHTTP(t, func(w http.ResponseWriter, r *http.Request) {
+				handler := notFound
+				switch {
+				case r.Host == "api.github.com":
+					handler = tt.api
+				case r.Host == "github.com" && r.URL.Path == repo+".atom" && tt.atom != nil:
+					handler = tt.atom
+				case r.Host == "github.com" && r.URL.Path == repo+"/latest" && tt.latest != nil:
+					handler = tt.latest
+				case r.Host == "github.com" && r.URL.Path == repo+"/expanded_assets/v1.2.0":
+					handler = func(w http.ResponseWriter, r *http.Request) {
+						w.Write([]byte(githubExpandedAssets("v1.2.0", asset, "Chrome++_v1.2.0_x86.7z")))
+					}
+				}
+				handler(w, r)
+			})
+
+			client := newTestMetadataClient(t, 0)
+			client.ChromePlusTag = tt.tag
+			release, base, err := client.fetchChromePlusRelease()
+			if tt.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+					t.Fatalf("err = %v, want %q", err, tt.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if release.TagName != tt.wantTag {
+				t.Fatalf("TagName = %q, want %q", release.TagName, tt.wantTag)
+			}
+			got := chromePlusAsset(release)
+			if got == nil || got.Name != asset || ResolveSource(base, got.BrowserDownloadURL) != assetURL {
+				t.Fatalf("资源 = %+v, base = %s, want %s", got, base, assetURL)
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !windows
+
+package internal
+
+import "os"
+
+// NewDefaultUI 返回当前平台的默认界面
+func NewDefaultUI() UI {
+	return NewTTYUI(os.Stdin, nil)
+}
+
+// HideConsole 隐藏控制台窗口（非 Windows 平台无需处理）
+func HideConsole() {}
+
+// AttachParentConsole 附加到父进程的控制台（非 Windows 平台已继承终端）
+func AttachParentConsole() {}
@@ -1,6 +1,9 @@
+//go:build windows
+
 package internal
 
 import (
+	"os"
 	"syscall"
 	"unsafe"
 )
@@ -14,6 +17,7 @@ var (
 	getConsoleWindow   = kernel32.NewProc("GetConsoleWindow")
 	showWindowProc     = user32.NewProc("ShowWindow")
 	allocConsole       = kernel32.NewProc("AllocConsole")
+	attachConsole      = kernel32.NewProc("AttachConsole")
 	setStdHandle       = kernel32.NewProc("SetStdHandle")
 	createFileW        = kernel32.NewProc("CreateFileW")
 	setConsoleOutputCP = kernel32.NewProc("SetConsoleOutputCP")
@@ -26,6 +30,7 @@ var (
 const (
 	MB_OK              = 0x00000000
 	MB_OKCANCEL        = 0x00000001
+	MB_YESNOCANCEL     = 0x00000003
 	MB_YESNO           = 0x00000004
 	MB_ICONINFORMATION = 0x00000040
 	MB_ICONQUESTION    = 0x00000020
@@ -49,6 +54,8 @@ const (
 
 	CP_UTF8 = 65001
 
+	ATTACH_PARENT_PROCESS = ^uintptr(0) // -1
+
 	// DPI Awareness
 	PROCESS_DPI_UNAWARE           = 0
 	PROCESS_SYSTEM_DPI_AWARE      = 1
@@ -77,7 +84,12 @@ func HideConsole() {
 }
 
 // ShowConsole 显示控制台窗口并重定向输出
-func ShowConsole() *ConsoleHandle {
+func (MessageBoxUI) ShowConsole() {
+	attachStdout(showConsole())
+}
+
+// showConsole 显示控制台窗口，新分配控制台时返回其句柄
+func showConsole() *ConsoleHandle {
 	hwnd, _, _ := getConsoleWindow.Call()
 	if hwnd == 0 {
 		// 没有控制台窗口，分配一个新的
@@ -113,6 +125,34 @@ func ShowConsole() *ConsoleHandle {
 	return nil
 }
 
+// AttachParentConsole 附加到父进程的控制台并重定向输出，用于命令行调用时输出结果
+func AttachParentConsole() {
+	attachStdout(attachParentConsole())
+}
+
+// attachParentConsole 附加到父进程的控制台，没有父控制台时返回 nil
+func attachParentConsole() *ConsoleHandle {
+	if ret, _, _ := attachConsole.Call(ATTACH_PARENT_PROCESS); ret == 0 {
+		return nil
+	}
+	setConsoleOutputCP.Call(CP_UTF8)
+
+	conout, _ := syscall.UTF16PtrFromString("CONOUT$")
+	handle, _, _ := createFileW.Call(
+		uintptr(unsafe.Pointer(conout)),
+		GENERIC_WRITE,
+		FILE_SHARE_WRITE,
+		0,
+		OPEN_EXISTING,
+		FILE_ATTRIBUTE_NORMAL,
+		0,
+	)
+	if handle == 0 || handle == ^uintptr(0) {
+		return nil
+	}
+	return &ConsoleHandle{handle: handle}
+}
+
 // ConsoleHandle 控制台句柄
 type ConsoleHandle struct {
 	handle uintptr
@@ -126,8 +166,24 @@ func (c *ConsoleHandle) GetHandle() uintptr {
 	return c.handle
 }
 
+// attachStdout 将标准输出/错误重定向到控制台句柄
+func attachStdout(console *ConsoleHandle) {
+	if console != nil && console.GetHandle() != 0 {
+		os.Stdout = os.NewFile(console.GetHandle(), "stdout")
+		os.Stderr = os.NewFile(console.GetHandle(), "stderr")
+	}
+}
+
+// MessageBoxUI 基于 Win32 MessageBox 的界面
+type MessageBoxUI struct{}
+
+// NewDefaultUI 返回当前平台的默认界面
+func NewDefaultUI() UI {
+	return &MessageBoxUI{}
+}
+
 // ShowConfirm 显示确认对话框
-func ShowConfirm(title, message string) bool {
+func (MessageBoxUI) ShowConfirm(title, message string) bool {
 	ret, _, _ := messageBoxW.Call(
 		0,
 		uintptr(unsafe.Pointer(syscall.StringToUTF16Ptr(message))),
@@ -137,8 +193,51 @@ func ShowConfirm(title, message string) bool {
 	return ret == IDYES
 }
 
+// ShowChoice 显示选项对话框
+// MessageBox 只有固定按钮，在消息中说明每个按钮对应的选项：
+// 两个选项时为"是"、"否"；三个选项时"是"、"否"、"取消"依次对应第 1、3、2 个选项，
+// 按 Esc 或关闭对话框返回"取消"，因此对应第 2 个选项（稍后提醒），不会误选第 3 个选项（跳过此版本）
+func (MessageBoxUI) ShowChoice(title, message string, choices []string) int {
+	if len(choices) > 3 {
+		choices = choices[:3]
+	}
+
+	// 按钮及对应的选项序号
+	type button struct {
+		label  string
+		id     uintptr
+		choice int
+	}
+	buttons := []button{{"是", IDYES, 0}, {"否", IDNO, 1}}
+	style := uintptr(MB_YESNO | MB_ICONQUESTION)
+	if len(choices) == 3 {
+		buttons = []button{{"是", IDYES, 0}, {"否", IDNO, 2}, {"取消", IDCANCEL, 1}}
+		style = MB_YESNOCANCEL | MB_ICONQUESTION
+	}
+
+	message += "\n"
+	for _, b := range buttons {
+		if b.choice < len(choices) {
+			message += "\n" + b.label + ": " + choices[b.choice]
+		}
+	}
+
+	ret, _, _ := messageBoxW.Call(
+		0,
+		uintptr(unsafe.Pointer(syscall.StringToUTF16Ptr(message))),
+		uintptr(unsafe.Pointer(syscall.StringToUTF16Ptr(title))),
+		style,
+	)
+	for _, b := range buttons {
+		if ret == b.id && b.choice < len(choices) {
+			return b.choice
+		}
+	}
+	return -1
+}
+
 // ShowInfo 显示信息对话框
-func ShowInfo(title, message string) {
+func (MessageBoxUI) ShowInfo(title, message string) {
 	messageBoxW.Call(
 		0,
 		uintptr(unsafe.Pointer(syscall.StringToUTF16Ptr(message))),
@@ -148,7 +247,7 @@ func ShowInfo(title, message string) {
 }
 
 // ShowError 显示错误对话框
-func ShowError(message string) {
+func (MessageBoxUI) ShowError(message string) {
 	messageBoxW.Call(
 		0,
 		uintptr(unsafe.Pointer(syscall.StringToUTF16Ptr(message))),
@@ -0,0 +1,215 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// magicPE PE 可执行文件的文件头魔数
+var magicPE = []byte("MZ")
+
+// fileVersionRegex 文件名中的版本号，如 Chrome++_v1.12.3_x86_x64_arm64.7z
+var fileVersionRegex = regexp.MustCompile(`(?i)v?(\d+(?:\.\d+){1,3})`)
+
+// DetectPackage 根据文件内容判断安装包所属组件
+// Chrome 安装程序为 PE 文件，或包含 Chrome-bin 的压缩包；Chrome++ 为包含 version.dll 的压缩包
+// 压缩包支持 7z、zip、tar.gz、tar.xz，按文件头识别格式
+func DetectPackage(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	header := make([]byte, len(magicPE))
+	n, _ := io.ReadFull(file, header)
+	file.Close()
+	if bytes.HasPrefix(header[:n], magicPE) {
+		return ComponentChrome, nil
+	}
+
+	if _, err := DetectArchiveFormat(path); err != nil {
+		return "", fmt.Errorf("无法识别的文件格式")
+	}
+	names, err := listArchive(path)
+	if err != nil {
+		return "", err
+	}
+	for _, name := range names {
+		name = strings.ToLower(strings.ReplaceAll(name, "\\", "/"))
+		if strings.HasSuffix(name, "app/version.dll") {
+			return ComponentChromePlus, nil
+		}
+		if strings.HasPrefix(name, "chrome-bin/") || name == "chrome.exe" || strings.HasSuffix(name, "/chrome.exe") {
+			return ComponentChrome, nil
+		}
+	}
+	return "", fmt.Errorf("无法识别的压缩包: 既不是 Chrome 也不是 Chrome++")
+}
+
+// chromePlusVersionFromName 从文件名中提取 Chrome++ 版本，以 Release tag 的形式（如 v1.12.3）返回
+func chromePlusVersionFromName(path string) string {
+	name := filepath.Base(path)
+	m := fileVersionRegex.FindStringSubmatch(strings.TrimSuffix(name, filepath.Ext(name)))
+	if m == nil {
+		return ""
+	}
+	return "v" + m[1]
+}
+
+// chromePlusPackageVersion 返回解压出的 Chrome++ 版本（Release tag 形式，与在线更新记录的版本一致）
+// 优先从安装包文件名中提取（Release 资源名包含 tag），文件名中没有版本时使用 version.dll 的文件版本
+func chromePlusPackageVersion(srcDir, packagePath string) string {
+	if version := chromePlusVersionFromName(packagePath); version != "" {
+		return version
+	}
+	if version, err := dllFileVersion(filepath.Join(srcDir, "version.dll")); err == nil {
+		return "v" + version
+	}
+	return ""
+}
+
+// dllFileVersion 读取 DLL 资源中 VS_FIXEDFILEINFO 的文件版本
+func dllFileVersion(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	// VS_FIXEDFILEINFO: dwSignature(0xFEEF04BD), dwStrucVersion, dwFileVersionMS, dwFileVersionLS
+	idx := bytes.Index(data, []byte{0xBD, 0x04, 0xEF, 0xFE})
+	if idx < 0 || idx+16 > len(data) {
+		return "", fmt.Errorf("未找到版本信息")
+	}
+	ms := binary.LittleEndian.Uint32(data[idx+8:])
+	ls := binary.LittleEndian.Uint32(data[idx+12:])
+
+	version := fmt.Sprintf("%d.%d.%d", ms>>16, ms&0xFFFF, ls>>16)
+	if build := ls & 0xFFFF; build != 0 {
+		version += fmt.Sprintf(".%d", build)
+	}
+	return version, nil
+}
+
+// installFromFile 从本地安装包离线安装 Chrome 或 Chrome++，不访问网络
+func (s *session) installFromFile(path, sha256Hex string) int {
+	cfg := s.cfg
+
+	// 识别出组件之前的失败以"安装包"通知
+	unknown := []ComponentChange{{}}
+	if !fileExists(path) {
+		return s.failUpdate(unknown, "", fmt.Errorf("安装包不存在: %s", path))
+	}
+	if sha256Hex != "" {
+		if err := VerifyFile(path, 0, sha256Hex); err != nil {
+			return s.failUpdate(unknown, "安装包校验失败: ", err)
+		}
+		s.say("SHA256 校验通过")
+	}
+
+	component, err := DetectPackage(path)
+	if err != nil {
+		return s.failUpdate(unknown, "", err)
+	}
+
+	// 预演模式只输出识别结果
+	if s.opts.DryRun {
+		fmt.Printf("将从 %s 安装 %s\n", path, componentName(component))
+		return ExitUpToDate
+	}
+
+	if isChromeRunning(cfg) {
+		fmt.Fprintln(os.Stderr, "Chrome 正在运行，请关闭浏览器后重试")
+		return ExitBrowserRunning
+	}
+
+	baseDir := programDir()
+	appDir := cfg.GetAppDir()
+	os.MkdirAll(filepath.Join(baseDir, "Data"), 0755)
+	os.MkdirAll(filepath.Join(baseDir, "Cache"), 0755)
+
+	state := ReadInstallState(cfg)
+	name := componentName(component)
+	s.say("正在安装 %s: %s", name, path)
+
+	change := ComponentChange{Component: component, From: cfg.Version}
+	if component == ComponentChromePlus {
+		change.From = cfg.ChromePlusVersion
+	}
+
+	// 先解压到临时目录并确定版本，确认无误后才修改安装目录
+	tempDir := appDir + "_install_temp"
+	defer os.RemoveAll(tempDir)
+
+	var srcDir, version string
+	switch component {
+	case ComponentChrome:
+		srcDir, version, err = unpackChrome(path, tempDir)
+		if err == nil && version == "" {
+			err = fmt.Errorf("安装包中未找到 Chrome 版本目录")
+		}
+	case ComponentChromePlus:
+		if srcDir, err = unpackChromePlus(path, tempDir); err == nil {
+			version = chromePlusPackageVersion(srcDir, path)
+		}
+	}
+	if err != nil {
+		return s.failUpdate([]ComponentChange{change}, fmt.Sprintf("解压 %s 失败: ", name), err)
+	}
+	change.To = version
+
+	// 备份将被替换的文件，复制失败时恢复
+	backup, err := backupFiles(baseDir, backupDir(), replaceFiles(cfg.ChromePath, component, version))
+	if err != nil {
+		return s.failUpdate([]ComponentChange{change}, "备份失败: ", err)
+	}
+	if component == ComponentChrome {
+		err = installChromeFiles(srcDir, appDir)
+	} else {
+		err = installChromePlusFiles(srcDir, appDir)
+	}
+	if err != nil {
+		return s.failUpdate([]ComponentChange{change}, "", backup.rollback(fmt.Errorf("安装 %s 失败: %w", name, err)))
+	}
+	backup.discard()
+
+	if component == ComponentChrome {
+		cfg.Version = version
+		cfg.SkippedChromeVersion = ""
+	} else {
+		cfg.ChromePlusVersion = version
+		cfg.SkippedChromePlusVersion = ""
+	}
+	cfg.SetSnoozeUntil(component, nil)
+	cfg.SetUpdateSeenAt(component, nil)
+
+	if err := cfg.Save(); err != nil {
+		return s.failUpdate([]ComponentChange{change}, "保存配置失败: ", err)
+	}
+	s.notifyResult([]ComponentChange{change}, nil)
+
+	// 创建 Chrome++ 配置快捷方式
+	if component == ComponentChromePlus && fileExists(cfg.GetChromePlusIniPath()) {
+		shortcutPath := filepath.Join(baseDir, "Chrome++配置.lnk")
+		if !fileExists(shortcutPath) {
+			CreateShortcut(cfg.GetChromePlusIniPath(), shortcutPath, "Chrome++ 配置文件")
+		}
+	}
+
+	// 安装 Chrome 后清理旧版本
+	if component == ComponentChrome {
+		if cleanup := planCleanup(cfg.ChromePath, state.VersionDirs, version, cfg.GetKeepVersions()); len(cleanup) > 0 {
+			s.cleanupOldVersions(cleanup)
+		}
+	}
+
+	if version == "" {
+		version = "未知版本"
+	}
+	s.info("安装完成", fmt.Sprintf("%s 已安装 %s", name, version))
+	return ExitUpdated
+}
@@ -0,0 +1,161 @@
+package internal
+
+import (
+	"encoding/binary"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testVersionDll 返回带有 VS_FIXEDFILEINFO 文件版本的 DLL 内容
+func testVersionDll(major, minor, patch, build uint16) string {
+	info := make([]byte, 16)
+	binary.LittleEndian.PutUint32(info[0:], 0xFEEF04BD)
+	binary.LittleEndian.PutUint32(info[4:], 0x00010000)
+	binary.LittleEndian.PutUint32(info[8:], uint32(major)<<16|uint32(minor))
+	binary.LittleEndian.PutUint32(info[12:], uint32(patch)<<16|uint32(build))
+	return "MZ chrome++ " + string(info)
+}
+
+func TestInstallFromFile(t *testing.T) {
+	tests := []struct {
+		name        string
+		file        string
+		files       map[string]string
+		wantCode    int
+		wantVersion string // 安装后配置中的 Chrome 或 Chrome++ 版本
+		wantChrome  string // 安装后 App/chrome.exe 的内容
+		wantDll     string // 安装后 App/version.dll 的内容
+	}{
+		{
+			name: "Chrome",
+			file: "chrome.zip",
+			files: map[string]string{
+				"Chrome-bin/chrome.exe":           "new chrome",
+				"Chrome-bin/122.0.1.0/chrome.dll": "new dll",
+			},
+			wantCode:    ExitUpdated,
+			wantVersion: "122.0.1.0",
+			wantChrome:  "new chrome",
+			wantDll:     "old chrome++",
+		},
+		{
+			name:        "Chrome 安装包缺少版本目录时不修改安装目录",
+			file:        "chrome.zip",
+			files:       map[string]string{"Chrome-bin/chrome.exe": "new chrome"},
+			wantCode:    ExitFailed,
+			wantVersion: "120.0.1.0",
+			wantChrome:  "old chrome",
+			wantDll:     "old chrome++",
+		},
+		{
+			name: "Chrome++ 优先使用文件名中的 tag",
+			file: "Chrome++_v1.0.0.zip",
+			files: map[string]string{
+				"x64/App/version.dll":  testVersionDll(1, 12, 3, 0),
+				"x64/App/chrome++.ini": "[general]",
+			},
+			wantCode:    ExitUpdated,
+			wantVersion: "v1.0.0",
+			wantChrome:  "old chrome",
+			wantDll:     testVersionDll(1, 12, 3, 0),
+		},
+		{
+			name: "Chrome++ 文件名带架构后缀",
+			file: "Chrome++_v1.11.0_x86_x64_arm64.zip",
+			files: map[string]string{
+				"x64/App/version.dll":  "new chrome++",
+				"x64/App/chrome++.ini": "[general]",
+			},
+			wantCode:    ExitUpdated,
+			wantVersion: "v1.11.0",
+			wantChrome:  "old chrome",
+			wantDll:     "new chrome++",
+		},
+		{
+			name: "Chrome++ 文件名没有版本时使用 version.dll 的版本",
+			file: "chrome_plus.zip",
+			files: map[string]string{
+				"x64/App/version.dll":  testVersionDll(1, 12, 3, 0),
+				"x64/App/chrome++.ini": "[general]",
+			},
+			wantCode:    ExitUpdated,
+			wantVersion: "v1.12.3",
+			wantChrome:  "old chrome",
+			wantDll:     testVersionDll(1, 12, 3, 0),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := setupTestInstall(t, nil)
+			pkg := filepath.Join(t.TempDir(), tt.file)
+			writeTestZip(t, pkg, tt.files)
+
+			if code := RunWithUI([]string{"install", "--from", pkg}, &ScriptedUI{}); code != tt.wantCode {
+				t.Fatalf("退出码 = %d, want %d", code, tt.wantCode)
+			}
+
+			cfg := loadTestConfig(t)
+			version := cfg.Version
+			if _, ok := tt.files["x64/App/version.dll"]; ok {
+				version = cfg.ChromePlusVersion
+			}
+			if version != tt.wantVersion {
+				t.Fatalf("版本 = %q, want %q", version, tt.wantVersion)
+			}
+			if got := readTestFile(t, filepath.Join(dir, "App", "chrome.exe")); got != tt.wantChrome {
+				t.Fatalf("chrome.exe = %q, want %q", got, tt.wantChrome)
+			}
+			if got := readTestFile(t, filepath.Join(dir, "App", "version.dll")); got != tt.wantDll {
+				t.Fatalf("version.dll = %q, want %q", got, tt.wantDll)
+			}
+			if fileExists(filepath.Join(dir, "App_install_temp")) || fileExists(backupDir()) {
+				t.Fatal("安装后应清理临时目录和备份")
+			}
+		})
+	}
+}
+
+func TestInstallFromFileThenCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		stored string // 离线安装前已记录的版本（旧版本记录不带 v）
+	}{
+		{name: "离线安装后不再提示同一版本", stored: "v1.0.0"},
+		{name: "已记录的版本不带 v 前缀", stored: strings.TrimPrefix(testLatestChromePlus, "v")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestInstall(t, func(cfg *Config) {
+				cfg.Version = testLatestChrome
+				cfg.ChromePlusVersion = tt.stored
+			})
+			if tt.stored == "v1.0.0" {
+				pkg := filepath.Join(t.TempDir(), "Chrome++_"+testLatestChromePlus+"_x86_x64_arm64.zip")
+				writeTestZip(t, pkg, map[string]string{
+					"x64/App/version.dll":  testVersionDll(1, 1, 0, 0),
+					"x64/App/chrome++.ini": "[general]",
+				})
+				if code := RunWithUI([]string{"install", "--from", pkg}, &ScriptedUI{}); code != ExitUpdated {
+					t.Fatalf("安装退出码 = %d, want %d", code, ExitUpdated)
+				}
+				if cfg := loadTestConfig(t); cfg.ChromePlusVersion != testLatestChromePlus {
+					t.Fatalf("ChromePlusVersion = %q, want %q", cfg.ChromePlusVersion, testLatestChromePlus)
+				}
+			}
+
+			ui := &ScriptedUI{}
+			if code := RunWithUI(nil, ui); code != ExitUpToDate {
+				t.Fatalf("检查退出码 = %d, want %d", code, ExitUpToDate)
+			}
+			if calls := ui.CallsTo("ShowChoice"); len(calls) != 0 {
+				t.Fatalf("已安装的版本不应再提示更新: %+v", calls)
+			}
+			if cfg := loadTestConfig(t); cfg.ChromePlusUpdateSeenAt != nil {
+				t.Fatalf("已安装的版本不应记录待更新时间: %v", cfg.ChromePlusUpdateSeenAt)
+			}
+		})
+	}
+}
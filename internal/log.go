@@ -0,0 +1,23 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// redirectOutputToFile 以追加方式打开日志文件，并将标准输出/错误重定向到该文件
+func redirectOutputToFile(path string) (*os.File, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	os.Stdout = file
+	os.Stderr = file
+	return file, nil
+}
+
+// logf 输出带时间戳的日志行
+func logf(format string, args ...interface{}) {
+	fmt.Printf("[%s] %s\n", time.Now().Format("2006-01-02 15:04:05"), fmt.Sprintf(format, args...))
+}
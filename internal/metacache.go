@@ -0,0 +1,306 @@
+package internal
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MetadataCache 更新元数据缓存，按 URL 保存响应内容和条件请求所需的校验信息
+type MetadataCache struct {
+	Entries map[string]*CacheEntry `json:"entries"`
+	Limited map[string]time.Time   `json:"limited,omitempty"` // 被限流的主机及可重试时间
+
+	mu    sync.Mutex
+	path  string
+	dirty bool
+}
+
+// CacheEntry 单个 URL 的缓存
+type CacheEntry struct {
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	FetchedAt    time.Time `json:"fetched_at"` // 最近一次向服务器确认的时间
+	Body         string    `json:"body"`
+}
+
+// MetadataCachePath 返回元数据缓存文件路径
+func MetadataCachePath() string {
+	return filepath.Join(programDir(), "metadata_cache.json")
+}
+
+// LoadMetadataCache 加载元数据缓存，文件不存在或损坏时返回空缓存
+func LoadMetadataCache(path string) *MetadataCache {
+	cache := &MetadataCache{
+		Entries: make(map[string]*CacheEntry),
+		Limited: make(map[string]time.Time),
+		path:    path,
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cache
+	}
+	if err := json.Unmarshal(data, cache); err != nil || cache.Entries == nil {
+		cache.Entries = make(map[string]*CacheEntry)
+	}
+	if cache.Limited == nil {
+		cache.Limited = make(map[string]time.Time)
+	}
+	return cache
+}
+
+// Save 缓存有变化时写回文件
+func (c *MetadataCache) Save() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.dirty {
+		return nil
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.path, data, 0644); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
+
+// get 获取缓存条目
+func (c *MetadataCache) get(url string) *CacheEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.Entries[url]
+}
+
+// put 写入缓存条目
+func (c *MetadataCache) put(url string, entry *CacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Entries[url] = entry
+	c.dirty = true
+}
+
+// limitedUntil 获取主机限流的解除时间，未限流时返回零值
+func (c *MetadataCache) limitedUntil(host string) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	until := c.Limited[host]
+	if time.Now().After(until) {
+		return time.Time{}
+	}
+	return until
+}
+
+// setLimited 记录主机限流的解除时间
+func (c *MetadataCache) setLimited(host string, until time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Limited[host] = until
+	c.dirty = true
+}
+
+// HTTPError 元数据请求返回了非预期的状态码
+type HTTPError struct {
+	StatusCode int
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("HTTP %d", e.StatusCode)
+}
+
+// RateLimitError 请求被服务器限流
+type RateLimitError struct {
+	Host  string
+	Until time.Time // 可重试的时间
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%s 请求次数超限，%s 后可重试", e.Host, e.Until.Local().Format("15:04:05"))
+}
+
+// parseRateLimit 从响应中解析限流信息（X-RateLimit-* 和 Retry-After），未限流时返回 false
+func parseRateLimit(resp *http.Response) (time.Time, bool) {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests &&
+		resp.StatusCode != http.StatusServiceUnavailable {
+		return time.Time{}, false
+	}
+
+	// Retry-After: 秒数或 HTTP 日期
+	if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil {
+			return time.Now().Add(time.Duration(seconds) * time.Second), true
+		}
+		if t, err := http.ParseTime(retryAfter); err == nil {
+			return t, true
+		}
+	}
+
+	// GitHub: X-RateLimit-Remaining 为 0 时，X-RateLimit-Reset 为解除限流的 Unix 时间
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return time.Unix(reset, 0), true
+		}
+		return time.Now().Add(time.Hour), true
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return time.Now().Add(time.Minute), true
+	}
+	return time.Time{}, false
+}
+
+// MetadataClient 获取更新元数据的客户端
+// 支持本地缓存、ETag/Last-Modified 条件请求和最短检查间隔
+type MetadataClient struct {
+	Cache       *MetadataCache // 元数据缓存，为 nil 时不缓存
+	MinInterval time.Duration  // 距上次确认不足该时长时直接使用缓存，不发起请求
+	Bypass      bool           // 忽略缓存，总是完整请求（check 命令）
+	GitHubToken string         // 调用 GitHub API 时使用的 Token
+
+	GitHubMirrors []string // GitHub 加速镜像
+	MirrorAPI     bool     // 元数据请求直连失败时是否通过镜像获取
+
+	URLRewrites []URLRewrite // Chrome 下载地址改写规则
+
+	ChromeDataURL        string // 自定义 Chrome 版本数据地址
+	ChromePlusReleaseURL string // 自定义 Chrome++ Release 信息地址
+	ChromePlusTag        string // 获取指定版本的 Chrome++（团队策略批准的版本），为空表示最新版本
+
+	TrustedKeys      []ed25519.PublicKey // 受信任的签名公钥
+	RequireSignature bool                // 自定义数据源的元数据必须带有受信任的签名
+}
+
+// NewMetadataClient 根据配置创建元数据客户端
+func NewMetadataClient(cfg *Config) *MetadataClient {
+	m := &MetadataClient{
+		Cache:       LoadMetadataCache(MetadataCachePath()),
+		MinInterval: cfg.GetCheckInterval(),
+	}
+	m.Configure(cfg)
+	return m
+}
+
+// Configure 按配置设置数据源、镜像和认证参数（无效的公钥被忽略，由调用方预先校验），缓存和检查间隔保持不变
+func (m *MetadataClient) Configure(cfg *Config) {
+	keys, _ := ParsePublicKeys(cfg.TrustedKeys)
+	m.GitHubToken = cfg.GetGitHubToken()
+
+	m.GitHubMirrors = cfg.GitHubMirrors
+	m.MirrorAPI = cfg.GitHubMirrorAPI
+
+	m.URLRewrites = cfg.URLRewrites
+
+	m.ChromeDataURL = cfg.ChromeDataURL
+	m.ChromePlusReleaseURL = cfg.ChromePlusReleaseURL
+
+	m.TrustedKeys = keys
+	m.RequireSignature = cfg.RequireSignature
+}
+
+// Fetch 获取 URL 内容，header 为附加的请求头
+// 被限流时记录解除时间，期间有缓存则返回缓存内容，否则返回 *RateLimitError
+func (m *MetadataClient) Fetch(rawURL string, header http.Header) ([]byte, error) {
+	var cached *CacheEntry
+	if m.Cache != nil && !m.Bypass {
+		cached = m.Cache.get(rawURL)
+	}
+
+	// 检查间隔内直接使用缓存
+	if cached != nil && m.MinInterval > 0 && time.Since(cached.FetchedAt) < m.MinInterval {
+		return []byte(cached.Body), nil
+	}
+
+	req, err := http.NewRequest("GET", rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// 主机仍在限流期内时不发起请求，有缓存则使用（可能已过期的）缓存
+	if m.Cache != nil {
+		if until := m.Cache.limitedUntil(req.URL.Host); !until.IsZero() {
+			if stale := m.Cache.get(rawURL); stale != nil {
+				return []byte(stale.Body), nil
+			}
+			return nil, &RateLimitError{Host: req.URL.Host, Until: until}
+		}
+	}
+
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if cached != nil {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	// 未修改，沿用缓存内容
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		updated := *cached
+		updated.FetchedAt = time.Now()
+		m.Cache.put(rawURL, &updated)
+		return []byte(cached.Body), nil
+	}
+
+	// 被限流，记录解除时间
+	if until, limited := parseRateLimit(resp); limited {
+		if m.Cache != nil {
+			m.Cache.setLimited(req.URL.Host, until)
+			if stale := m.Cache.get(rawURL); stale != nil {
+				return []byte(stale.Body), nil
+			}
+		}
+		return nil, &RateLimitError{Host: req.URL.Host, Until: until}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &HTTPError{StatusCode: resp.StatusCode}
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.Cache != nil {
+		m.Cache.put(rawURL, &CacheEntry{
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+			FetchedAt:    time.Now(),
+			Body:         string(body),
+		})
+	}
+	return body, nil
+}
+
+// FetchJSON 获取 URL 内容并解析为 JSON
+func (m *MetadataClient) FetchJSON(rawURL string, header http.Header, v interface{}) error {
+	body, err := m.Fetch(rawURL, header)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
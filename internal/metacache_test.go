@@ -0,0 +1,260 @@
+package internal
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+// metadataServer 记录请求的元数据替身服务
+type metadataServer struct {
+	*httptest.Server
+
+	mu          sync.Mutex
+	requests    int
+	ifNoneMatch []string
+	respond     func(w http.ResponseWriter, r *http.Request)
+}
+
+// newMetadataServer 启动元数据替身服务，respond 为 nil 时返回带 ETag 的内容，条件请求匹配时返回 304
+func newMetadataServer(t *testing.T, respond func(w http.ResponseWriter, r *http.Request)) *metadataServer {
+	t.Helper()
+	s := &metadataServer{respond: respond}
+	if s.respond == nil {
+		s.respond = func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+			w.Write([]byte(`{"version":"v1"}`))
+		}
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests++
+		s.ifNoneMatch = append(s.ifNoneMatch, r.Header.Get("If-None-Match"))
+		s.mu.Unlock()
+		s.respond(w, r)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// count 返回收到的请求数
+func (s *metadataServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// newTestMetadataClient 返回使用临时缓存文件的元数据客户端
+func newTestMetadataClient(t *testing.T, interval time.Duration) *MetadataClient {
+	t.Helper()
+	return &MetadataClient{
+		Cache:       LoadMetadataCache(filepath.Join(t.TempDir(), "metadata_cache.json")),
+		MinInterval: interval,
+	}
+}
+
+func TestMetadataFetchNotModified(t *testing.T) {
+	server := newMetadataServer(t, nil)
+	client := newTestMetadataClient(t, 0)
+
+	first, err := client.Fetch(server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fetchedAt := client.Cache.get(server.URL).FetchedAt
+
+	second, err := client.Fetch(server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(second) != string(first) {
+		t.Fatalf("304 时应返回缓存内容, got %q", second)
+	}
+	if server.count() != 2 || server.ifNoneMatch[1] != `"v1"` {
+		t.Fatalf("请求 %d 次, If-None-Match = %q", server.count(), server.ifNoneMatch)
+	}
+	if entry := client.Cache.get(server.URL); entry.ETag != `"v1"` || !entry.FetchedAt.After(fetchedAt) {
+		t.Fatalf("304 后缓存条目 = %+v", entry)
+	}
+
+	// check 命令忽略缓存，不发送条件请求
+	client.Bypass = true
+	if _, err := client.Fetch(server.URL, nil); err != nil {
+		t.Fatal(err)
+	}
+	if server.ifNoneMatch[2] != "" {
+		t.Fatalf("忽略缓存时 If-None-Match = %q", server.ifNoneMatch[2])
+	}
+}
+
+func TestMetadataFetchMinInterval(t *testing.T) {
+	server := newMetadataServer(t, nil)
+	client := newTestMetadataClient(t, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		if body, err := client.Fetch(server.URL, nil); err != nil || string(body) != `{"version":"v1"}` {
+			t.Fatalf("第 %d 次: body = %q, err = %v", i+1, body, err)
+		}
+	}
+	if server.count() != 1 {
+		t.Fatalf("检查间隔内请求 %d 次, want 1", server.count())
+	}
+
+	// 超过检查间隔后发送条件请求
+	entry := *client.Cache.get(server.URL)
+	entry.FetchedAt = time.Now().Add(-2 * time.Hour)
+	client.Cache.put(server.URL, &entry)
+	if _, err := client.Fetch(server.URL, nil); err != nil {
+		t.Fatal(err)
+	}
+	if server.count() != 2 || server.ifNoneMatch[1] != `"v1"` {
+		t.Fatalf("超过检查间隔后请求 %d 次, If-None-Match = %q", server.count(), server.ifNoneMatch)
+	}
+}
+
+func TestMetadataFetchRateLimit(t *testing.T) {
+	reset := time.Now().Add(30 * time.Minute).Truncate(time.Second)
+
+	tests := []struct {
+		name      string
+		status    int
+		header    map[string]string
+		wantUntil time.Time // 为零值表示不视为限流
+	}{
+		{
+			name:      "Retry-After 秒数",
+			status:    http.StatusTooManyRequests,
+			header:    map[string]string{"Retry-After": "600"},
+			wantUntil: time.Now().Add(10 * time.Minute),
+		},
+		{
+			name:      "Retry-After 日期",
+			status:    http.StatusServiceUnavailable,
+			header:    map[string]string{"Retry-After": reset.UTC().Format(http.TimeFormat)},
+			wantUntil: reset,
+		},
+		{
+			name:      "GitHub X-RateLimit-Reset",
+			status:    http.StatusForbidden,
+			header:    map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": strconv.FormatInt(reset.Unix(), 10)},
+			wantUntil: reset,
+		},
+		{
+			name:      "429 没有限流信息",
+			status:    http.StatusTooManyRequests,
+			wantUntil: time.Now().Add(time.Minute),
+		},
+		{
+			name:   "403 不是限流",
+			status: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newMetadataServer(t, func(w http.ResponseWriter, r *http.Request) {
+				for k, v := range tt.header {
+					w.Header().Set(k, v)
+				}
+				w.WriteHeader(tt.status)
+			})
+			client := newTestMetadataClient(t, 0)
+			_, err := client.Fetch(server.URL+"/data.json", nil)
+
+			if tt.wantUntil.IsZero() {
+				var httpErr *HTTPError
+				if !errors.As(err, &httpErr) || httpErr.StatusCode != tt.status {
+					t.Fatalf("err = %v, want HTTP %d", err, tt.status)
+				}
+				return
+			}
+			var limited *RateLimitError
+			if !errors.As(err, &limited) {
+				t.Fatalf("err = %v, want *RateLimitError", err)
+			}
+			if diff := limited.Until.Sub(tt.wantUntil); diff < -5*time.Second || diff > 5*time.Second {
+				t.Fatalf("Until = %v, want %v", limited.Until, tt.wantUntil)
+			}
+
+			// 限流期内同一主机的其他地址不发起请求，有缓存时使用缓存
+			other := server.URL + "/chrome_plus.json"
+			client.Cache.put(other, &CacheEntry{FetchedAt: time.Now().Add(-24 * time.Hour), Body: "cached"})
+			if body, err := client.Fetch(other, nil); err != nil || string(body) != "cached" {
+				t.Fatalf("限流期内 body = %q, err = %v, want 缓存内容", body, err)
+			}
+			if _, err := client.Fetch(server.URL+"/data.json", nil); !errors.As(err, &limited) {
+				t.Fatalf("限流期内没有缓存时 err = %v, want *RateLimitError", err)
+			}
+			if server.count() != 1 {
+				t.Fatalf("限流期内请求 %d 次, want 1", server.count())
+			}
+		})
+	}
+}
+
+func TestMetadataCachePersistence(t *testing.T) {
+	server := newMetadataServer(t, nil)
+	limited := newMetadataServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "3600")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	path := filepath.Join(t.TempDir(), "metadata_cache.json")
+
+	client := &MetadataClient{Cache: LoadMetadataCache(path)}
+	if _, err := client.Fetch(server.URL, nil); err != nil {
+		t.Fatal(err)
+	}
+	client.Fetch(limited.URL, nil)
+	if err := client.Cache.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 下次运行从文件加载缓存：继续发送条件请求，限流记录仍然有效
+	reloaded := &MetadataClient{Cache: LoadMetadataCache(path)}
+	body, err := reloaded.Fetch(server.URL, nil)
+	if err != nil || string(body) != `{"version":"v1"}` {
+		t.Fatalf("body = %q, err = %v", body, err)
+	}
+	if server.count() != 2 || server.ifNoneMatch[1] != `"v1"` {
+		t.Fatalf("请求 %d 次, If-None-Match = %q", server.count(), server.ifNoneMatch)
+	}
+	var rateErr *RateLimitError
+	if _, err := reloaded.Fetch(limited.URL, nil); !errors.As(err, &rateErr) || limited.count() != 1 {
+		t.Fatalf("err = %v, 请求 %d 次, want 限流期内不请求", err, limited.count())
+	}
+
+	// 损坏的缓存文件视为空缓存
+	writeTestFile(t, path, "{broken")
+	if cache := LoadMetadataCache(path); len(cache.Entries) != 0 || len(cache.Limited) != 0 {
+		t.Fatalf("损坏的缓存 = %+v", cache)
+	}
+}
+
+func TestMetadataCacheLimitedPerHost(t *testing.T) {
+	server := newMetadataServer(t, nil)
+	client := newTestMetadataClient(t, 0)
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Cache.setLimited("api.github.com", time.Now().Add(time.Hour))
+	client.Cache.setLimited(u.Host, time.Now().Add(-time.Minute))
+
+	// 其他主机的限流和已过期的限流不影响请求
+	if _, err := client.Fetch(server.URL, nil); err != nil {
+		t.Fatal(err)
+	}
+	if server.count() != 1 {
+		t.Fatalf("请求 %d 次, want 1", server.count())
+	}
+}
@@ -0,0 +1,87 @@
+package internal
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// githubHosts 可通过 GitHub 加速镜像访问的主机
+var githubHosts = map[string]bool{
+	"github.com":                    true,
+	"api.github.com":                true,
+	"raw.githubusercontent.com":     true,
+	"objects.githubusercontent.com": true,
+	"codeload.github.com":           true,
+}
+
+// isGitHubURL 判断是否为 GitHub 的地址
+func isGitHubURL(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	return githubHosts[strings.ToLower(u.Hostname())]
+}
+
+// applyGitHubMirror 按镜像规则改写 GitHub 地址
+// 规则包含 {url} 或 {path} 时作为模板替换（{url} 为完整地址，{path} 为去掉协议和主机后的路径），否则作为前缀
+func applyGitHubMirror(mirror, rawURL string) string {
+	mirror = strings.TrimSpace(mirror)
+	if strings.Contains(mirror, "{url}") || strings.Contains(mirror, "{path}") {
+		path := rawURL
+		if u, err := url.Parse(rawURL); err == nil {
+			path = strings.TrimPrefix(u.RequestURI(), "/")
+		}
+		return strings.NewReplacer("{url}", rawURL, "{path}", path).Replace(mirror)
+	}
+	if !strings.HasSuffix(mirror, "/") {
+		mirror += "/"
+	}
+	return mirror + rawURL
+}
+
+// GitHubMirrorURLs 生成 GitHub 地址的候选下载地址：镜像地址在前，原地址在最后
+// 非 GitHub 地址或未配置镜像时只返回原地址
+func GitHubMirrorURLs(mirrors []string, rawURL string) []string {
+	if !isGitHubURL(rawURL) {
+		return []string{rawURL}
+	}
+
+	urls := make([]string, 0, len(mirrors)+1)
+	for _, mirror := range mirrors {
+		if strings.TrimSpace(mirror) == "" {
+			continue
+		}
+		if candidate := applyGitHubMirror(mirror, rawURL); !containsString(urls, candidate) {
+			urls = append(urls, candidate)
+		}
+	}
+	if !containsString(urls, rawURL) {
+		urls = append(urls, rawURL)
+	}
+	return urls
+}
+
+// fetchGitHub 获取 GitHub 元数据
+// 直连失败且启用了 API 镜像时依次尝试镜像地址，镜像请求不携带 Authorization，避免 Token 泄露给第三方
+func (m *MetadataClient) fetchGitHub(rawURL string, header http.Header) ([]byte, error) {
+	body, err := m.Fetch(rawURL, header)
+	if err == nil || !m.MirrorAPI || len(m.GitHubMirrors) == 0 || !isGitHubURL(rawURL) {
+		return body, err
+	}
+
+	mirrorHeader := header.Clone()
+	if mirrorHeader != nil {
+		mirrorHeader.Del("Authorization")
+	}
+	for _, candidate := range GitHubMirrorURLs(m.GitHubMirrors, rawURL) {
+		if candidate == rawURL {
+			continue
+		}
+		if body, mirrorErr := m.Fetch(candidate, mirrorHeader); mirrorErr == nil {
+			return body, nil
+		}
+	}
+	return nil, err
+}
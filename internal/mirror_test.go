@@ -0,0 +1,150 @@
+package internal
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestGitHubMirrorURLs(t *testing.T) {
+	asset := "https://github.com/" + chromePlusRepo + "/releases/download/v1.2.0/Chrome++_v1.2.0_x64.7z"
+
+	tests := []struct {
+		name    string
+		mirrors []string
+		rawURL  string
+		want    []string
+	}{
+		{
+			name:   "未配置镜像",
+			rawURL: asset,
+			want:   []string{asset},
+		},
+		{
+			name:    "前缀镜像",
+			mirrors: []string{"https://ghproxy.example"},
+			rawURL:  asset,
+			want:    []string{"https://ghproxy.example/" + asset, asset},
+		},
+		{
+			name:    "{url} 和 {path} 模板",
+			mirrors: []string{"https://a.example/?u={url}", "https://b.example/gh/{path}"},
+			rawURL:  asset,
+			want: []string{
+				"https://a.example/?u=" + asset,
+				"https://b.example/gh/" + chromePlusRepo + "/releases/download/v1.2.0/Chrome++_v1.2.0_x64.7z",
+				asset,
+			},
+		},
+		{
+			name:    "Release API 地址",
+			mirrors: []string{"https://ghproxy.example/"},
+			rawURL:  chromePlusAPI,
+			want:    []string{"https://ghproxy.example/" + chromePlusAPI, chromePlusAPI},
+		},
+		{
+			name:    "忽略空白和重复的镜像",
+			mirrors: []string{" ", "https://ghproxy.example/", "https://ghproxy.example"},
+			rawURL:  asset,
+			want:    []string{"https://ghproxy.example/" + asset, asset},
+		},
+		{
+			name:    "非 GitHub 地址不改写",
+			mirrors: []string{"https://ghproxy.example/"},
+			rawURL:  "https://mirror.lan/chrome_plus/Chrome++_v1.2.0_x64.7z",
+			want:    []string{"https://mirror.lan/chrome_plus/Chrome++_v1.2.0_x64.7z"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := GitHubMirrorURLs(tt.mirrors, tt.rawURL)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Fatalf("GitHubMirrorURLs =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestGitHubMirrorAPI(t *testing.T) {
+	digest := strings.Repeat("AB", 32)
+	asset := "https://github.com/" + chromePlusRepo + "/releases/download/v1.2.0/Chrome++_v1.2.0_x64.7z"
+	release := GitHubRelease{
+		TagName: "v1.2.0",
+		Assets:  []Asset{{Name: "Chrome++_v1.2.0_x64.7z", Size: 1024, BrowserDownloadURL: asset, Digest: "sha256:" + digest}},
+	}
+
+	tests := []struct {
+		name           string
+		mirrorAPI      bool
+		wantChromePlus string // 为空表示 Chrome++ 信息获取失败
+	}{
+		{name: "直连失败时通过镜像获取", mirrorAPI: true, wantChromePlus: "v1.2.0"},
+		{name: "未启用 API 镜像"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var mu sync.Mutex
+			auth := make(map[string][]string) // 各主机收到的 Authorization
+			routeHTTP(t, func(w http.ResponseWriter, r *http.Request) {
+				mu.Lock()
+				auth[r.Host] = append(auth[r.Host], r.Header.Get("Authorization"))
+				mu.Unlock()
+				switch {
+				case r.Host == "raw.githubusercontent.com":
+					json.NewEncoder(w).Encode(ChromeData{getChromeDataKey("stable"): {Version: testLatestChrome, URLs: []string{"https://dl.google.com/chrome.exe"}}})
+				case r.Host == "ghproxy.example":
+					w.WriteHeader(http.StatusBadGateway)
+				case r.Host == "mirror.example" && r.URL.Path == "/gh/repos/"+chromePlusRepo+"/releases/latest":
+					json.NewEncoder(w).Encode(release)
+				default:
+					w.WriteHeader(http.StatusInternalServerError)
+				}
+			})
+
+			client := newTestMetadataClient(t, 0)
+			client.GitHubToken = "secret"
+			client.GitHubMirrors = []string{"https://ghproxy.example/", "https://mirror.example/gh/{path}"}
+			client.MirrorAPI = tt.mirrorAPI
+			info, err := client.GetLatestVersion("stable")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			if got := auth["api.github.com"]; len(got) == 0 || got[0] != "Bearer secret" {
+				t.Fatalf("GitHub API 的 Authorization = %q", got)
+			}
+			if !tt.mirrorAPI {
+				if info.ChromePlusVersion != "" || auth["ghproxy.example"] != nil || auth["mirror.example"] != nil {
+					t.Fatalf("未启用 API 镜像时不应请求镜像: %+v, 请求 %v", info, auth)
+				}
+				return
+			}
+			for _, host := range []string{"ghproxy.example", "mirror.example"} {
+				if got := auth[host]; len(got) == 0 || strings.Join(got, "") != "" {
+					t.Fatalf("%s 收到的 Authorization = %q, want 请求且不携带 Token", host, got)
+				}
+			}
+
+			if info.ChromePlusVersion != tt.wantChromePlus {
+				t.Fatalf("ChromePlusVersion = %q (%s)", info.ChromePlusVersion, info.ChromePlusError)
+			}
+			if info.ChromePlusSHA256 != strings.ToLower(digest) || info.ChromePlusSize != 1024 {
+				t.Fatalf("ChromePlusSHA256 = %q, ChromePlusSize = %d, 应沿用资源摘要和大小", info.ChromePlusSHA256, info.ChromePlusSize)
+			}
+			want := []string{
+				"https://ghproxy.example/" + asset,
+				"https://mirror.example/gh/" + chromePlusRepo + "/releases/download/v1.2.0/Chrome++_v1.2.0_x64.7z",
+				asset,
+			}
+			if info.ChromePlusURL != asset || strings.Join(info.ChromePlusURLs, "\n") != strings.Join(want, "\n") {
+				t.Fatalf("ChromePlusURL = %s, ChromePlusURLs = %q", info.ChromePlusURL, info.ChromePlusURLs)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package internal
+
+import (
+	"crypto/ed25519"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// offlineFilesDir 离线镜像中存放安装包的子目录
+const offlineFilesDir = "files"
+
+// MirrorOptions mirror 命令选项
+type MirrorOptions struct {
+	Out           string             // 输出目录
+	Channels      []string           // 通道，如 stable、beta
+	Arches        []string           // 架构，如 x64、x86、arm64
+	ChromePlusTag string             // 固定的 Chrome++ 版本，为空表示最新版本
+	SignKey       ed25519.PrivateKey // 签名私钥，设置后同时生成 .sig 签名文件
+}
+
+// chromeChannelName 规范化通道名称，未知通道视为 stable
+func chromeChannelName(channel string) string {
+	switch c := strings.ToLower(channel); c {
+	case "beta", "dev", "canary":
+		return c
+	default:
+		return "stable"
+	}
+}
+
+// chromeChannelKey 返回 data.json 中通道和架构对应的 key，如 win_stable_x64
+func chromeChannelKey(channel, arch string) string {
+	return fmt.Sprintf("win_%s_%s", strings.ToLower(channel), strings.ToLower(arch))
+}
+
+// BuildOfflineMirror 下载安装包并生成离线镜像目录
+// 目录结构: data.json（与 chrome_installer 格式相同）、chrome_plus.json（与 GitHub Release API 格式相同）、files/（安装包），
+// 其中的下载地址为相对路径，可直接作为 chrome_data_url / chrome_plus_release_url 使用（本地目录或静态 Web 服务器）
+func BuildOfflineMirror(client *MetadataClient, opts MirrorOptions, threads int, say func(format string, args ...interface{})) error {
+	filesDir := filepath.Join(opts.Out, offlineFilesDir)
+	if err := os.MkdirAll(filesDir, 0755); err != nil {
+		return err
+	}
+	keep := make(map[string]bool)
+
+	// Chrome
+	dataURL := client.chromeDataSource()
+	upstream, err := client.fetchChromeData(dataURL)
+	if err != nil {
+		return fmt.Errorf("获取 Chrome 版本失败: %w", err)
+	}
+	data := make(ChromeData)
+	for _, channel := range opts.Channels {
+		for _, arch := range opts.Arches {
+			key := chromeChannelKey(channel, arch)
+			entry, ok := upstream[key]
+			if !ok {
+				say("上游没有 %s 的数据，已跳过", key)
+				continue
+			}
+
+			name := fmt.Sprintf("chrome_installer_%s_%s_%s.exe", strings.ToLower(channel), strings.ToLower(arch), entry.Version)
+			if err := mirrorFile(client.chromeURLs(dataURL, entry.URLs), filepath.Join(filesDir, name),
+				entry.Size, entry.SHA256, threads, say); err != nil {
+				return fmt.Errorf("下载 Chrome %s 失败: %w", key, err)
+			}
+			keep[name] = true
+
+			entry.URLs = []string{offlineFilesDir + "/" + name}
+			data[key] = entry
+		}
+	}
+	if len(data) == 0 {
+		return fmt.Errorf("没有可用的 Chrome 通道")
+	}
+	if err := writeJSONFile(filepath.Join(opts.Out, chromeDataFile), data); err != nil {
+		return err
+	}
+
+	// Chrome++
+	var release *GitHubRelease
+	base := chromePlusAPI
+	if opts.ChromePlusTag != "" {
+		base = chromePlusTagAPI + opts.ChromePlusTag
+		release, err = client.fetchRelease(base, false)
+	} else {
+		release, base, err = client.fetchChromePlusRelease()
+	}
+	if err != nil {
+		return fmt.Errorf("获取 Chrome++ 版本失败: %w", err)
+	}
+	asset := chromePlusAsset(release)
+	if asset == nil {
+		return fmt.Errorf("Chrome++ %s 中没有找到支持的压缩包", release.TagName)
+	}
+	urls := GitHubMirrorURLs(client.GitHubMirrors, ResolveSource(base, asset.BrowserDownloadURL))
+	plusPath := filepath.Join(filesDir, asset.Name)
+	if err := mirrorFile(urls, plusPath, asset.Size, asset.SHA256(), threads, say); err != nil {
+		return fmt.Errorf("下载 Chrome++ %s 失败: %w", release.TagName, err)
+	}
+	keep[asset.Name] = true
+
+	// 上游没有摘要时记录下载文件的 SHA256，使用镜像的客户端据此校验
+	digest := asset.Digest
+	if asset.SHA256() == "" {
+		sha, err := fileSHA256(plusPath)
+		if err != nil {
+			return err
+		}
+		digest = "sha256:" + sha
+		say("上游没有 Chrome++ %s 的 SHA256，已记录下载文件的 SHA256: %s", release.TagName, sha)
+	}
+	plus := &GitHubRelease{
+		TagName: release.TagName,
+		Assets:  []Asset{{Name: asset.Name, Size: asset.Size, BrowserDownloadURL: offlineFilesDir + "/" + asset.Name, Digest: digest}},
+	}
+	if err := writeJSONFile(filepath.Join(opts.Out, chromePlusRelFile), plus); err != nil {
+		return err
+	}
+
+	// 签名元数据
+	if opts.SignKey != nil {
+		for _, name := range []string{chromeDataFile, chromePlusRelFile} {
+			if err := SignFile(opts.SignKey, filepath.Join(opts.Out, name)); err != nil {
+				return fmt.Errorf("签名 %s 失败: %w", name, err)
+			}
+		}
+		say("已生成元数据签名")
+	}
+
+	// 删除不再引用的旧安装包
+	entries, _ := os.ReadDir(filesDir)
+	for _, entry := range entries {
+		if !entry.IsDir() && !keep[entry.Name()] {
+			if err := os.Remove(filepath.Join(filesDir, entry.Name())); err == nil {
+				say("已删除旧文件: %s", entry.Name())
+			}
+		}
+	}
+
+	var names []string
+	for name := range keep {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	say("离线镜像已生成: %s\n%s", opts.Out, strings.Join(names, "\n"))
+	return nil
+}
+
+// mirrorFile 下载文件到离线镜像，已存在且校验通过时跳过
+func mirrorFile(urls []string, dest string, size int64, sha256Hex string, threads int, say func(format string, args ...interface{})) error {
+	name := filepath.Base(dest)
+	if fileExists(dest) {
+		if err := VerifyFile(dest, size, sha256Hex); err == nil {
+			say("%s 已存在，跳过下载", name)
+			return nil
+		}
+		os.Remove(dest)
+	}
+
+	say("正在下载 %s", name)
+	err := DownloadVerified(urls, dest, size, sha256Hex, threads, progressPrinter(name, false))
+	fmt.Println()
+	return err
+}
+
+// writeJSONFile 以缩进格式写入 JSON 文件
+func writeJSONFile(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
@@ -0,0 +1,56 @@
+package internal
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildOfflineMirrorChromePlusDigest(t *testing.T) {
+	tests := []struct {
+		name    string
+		digest  func(sha string) string // 上游资源摘要
+		wantErr string
+	}{
+		{name: "上游有摘要", digest: func(sha string) string { return "sha256:" + strings.ToUpper(sha) }},
+		{name: "上游没有摘要时记录下载文件的 SHA256", digest: func(string) string { return "" }},
+		{name: "摘要不符", digest: func(string) string { return "sha256:" + strings.Repeat("0", 64) }, wantErr: "SHA256 校验失败"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := setupTestInstall(t, nil)
+			releasePath := filepath.Join(dir, "source", chromePlusRelFile)
+			var release GitHubRelease
+			if err := json.Unmarshal([]byte(readTestFile(t, releasePath)), &release); err != nil {
+				t.Fatal(err)
+			}
+			sha := testFileSHA256(t, release.Assets[0].BrowserDownloadURL)
+			release.Assets[0].Digest = tt.digest(sha)
+			writeTestJSON(t, releasePath, release)
+
+			out := filepath.Join(dir, "offline")
+			client := NewMetadataClient(loadTestConfig(t))
+			opts := MirrorOptions{Out: out, Channels: []string{"stable"}, Arches: []string{"x64"}}
+			err := BuildOfflineMirror(client, opts, 1, func(string, ...interface{}) {})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var mirrored GitHubRelease
+			if err := json.Unmarshal([]byte(readTestFile(t, filepath.Join(out, chromePlusRelFile))), &mirrored); err != nil {
+				t.Fatal(err)
+			}
+			if len(mirrored.Assets) != 1 || mirrored.Assets[0].SHA256() != sha {
+				t.Fatalf("Assets = %+v, want SHA256 %s", mirrored.Assets, sha)
+			}
+		})
+	}
+}
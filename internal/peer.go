@@ -0,0 +1,341 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// peerDiscoveryPort 局域网节点发现使用的 UDP 端口
+	peerDiscoveryPort = 8781
+	// peerDiscoveryTimeout 等待节点应答的时长
+	peerDiscoveryTimeout = time.Second
+)
+
+// 节点发现消息类型
+const (
+	peerDiscover = "chromego-discover" // 广播查询
+	peerAnnounce = "chromego-announce" // 节点应答：HTTP 端口和持有的安装包
+)
+
+// peerMessage 节点发现消息
+type peerMessage struct {
+	Type     string   `json:"type"`
+	Port     int      `json:"port,omitempty"`     // 节点的 HTTP 端口
+	Packages []string `json:"packages,omitempty"` // 节点持有且已校验的安装包 SHA256
+}
+
+// Peer 局域网中的 ChromeGo 缓存服务节点
+type Peer struct {
+	Addr     string   // HTTP 地址（主机:端口）
+	Packages []string // 持有的安装包 SHA256
+}
+
+// URL 返回节点上指定安装包的下载地址
+func (p Peer) URL(sha256Hex string) string {
+	return "http://" + p.Addr + "/peer/" + strings.ToLower(sha256Hex)
+}
+
+// broadcastAddrs 返回本机各 IPv4 网络的广播地址（包括 255.255.255.255）
+func broadcastAddrs(port int) []*net.UDPAddr {
+	addrs := []*net.UDPAddr{{IP: net.IPv4bcast, Port: port}}
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return addrs
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagBroadcast == 0 {
+			continue
+		}
+		ifAddrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range ifAddrs {
+			ipNet, ok := a.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			ip := ipNet.IP.To4()
+			if ip == nil || ip.IsLoopback() {
+				continue
+			}
+			bcast := make(net.IP, 4)
+			for i := range ip {
+				bcast[i] = ip[i] | ^ipNet.Mask[len(ipNet.Mask)-4+i]
+			}
+			addrs = append(addrs, &net.UDPAddr{IP: bcast, Port: port})
+		}
+	}
+	return addrs
+}
+
+// DiscoverPeers 向指定地址（通常为广播地址）发送查询，收集 timeout 内应答的节点
+func DiscoverPeers(targets []*net.UDPAddr, timeout time.Duration) ([]Peer, error) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	query, _ := json.Marshal(peerMessage{Type: peerDiscover})
+	for _, target := range targets {
+		conn.WriteToUDP(query, target)
+	}
+
+	var peers []Peer
+	seen := make(map[string]bool)
+	buf := make([]byte, 64*1024)
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	for {
+		n, src, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			// 超时，结束收集
+			break
+		}
+		var msg peerMessage
+		if json.Unmarshal(buf[:n], &msg) != nil || msg.Type != peerAnnounce || msg.Port <= 0 {
+			continue
+		}
+		addr := net.JoinHostPort(src.IP.String(), strconv.Itoa(msg.Port))
+		if seen[addr] {
+			continue
+		}
+		seen[addr] = true
+		peers = append(peers, Peer{Addr: addr, Packages: msg.Packages})
+	}
+	return peers, nil
+}
+
+// PeerURLs 返回持有指定安装包的节点下载地址
+func PeerURLs(peers []Peer, sha256Hex string) []string {
+	if sha256Hex == "" {
+		return nil
+	}
+	var urls []string
+	for _, p := range peers {
+		for _, pkg := range p.Packages {
+			if strings.EqualFold(pkg, sha256Hex) {
+				urls = append(urls, p.URL(sha256Hex))
+				break
+			}
+		}
+	}
+	return urls
+}
+
+// answerPeers 监听节点发现查询，应答 HTTP 端口和当前持有的安装包，直到连接关闭
+func answerPeers(conn *net.UDPConn, httpPort int, packages func() []string) {
+	buf := make([]byte, 2048)
+	for {
+		n, src, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return
+		}
+		var msg peerMessage
+		if json.Unmarshal(buf[:n], &msg) != nil || msg.Type != peerDiscover {
+			continue
+		}
+		reply, _ := json.Marshal(peerMessage{Type: peerAnnounce, Port: httpPort, Packages: packages()})
+		conn.WriteToUDP(reply, src)
+	}
+}
+
+// fileHash 已计算的安装包 SHA256，文件大小和修改时间不变时沿用
+type fileHash struct {
+	size    int64
+	modTime time.Time
+	sha256  string
+}
+
+// verifiedPackages 返回缓存目录中已校验的安装包，SHA256 -> 本地路径
+// 缓存目录中的文件都是校验通过后才写入的，这里只计算其 SHA256 用于节点间共享
+func (c *CacheServer) verifiedPackages() map[string]string {
+	result := make(map[string]string)
+	entries, err := os.ReadDir(c.Dir)
+	if err != nil {
+		return result
+	}
+
+	c.hashMu.Lock()
+	defer c.hashMu.Unlock()
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasSuffix(entry.Name(), ".part") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if sha := c.hashLocked(entry.Name(), info); sha != "" {
+			result[sha] = filepath.Join(c.Dir, entry.Name())
+		}
+	}
+	return result
+}
+
+// cachedSHA256 返回缓存目录中指定安装包的 SHA256，未缓存或大小与 size（非 0 时）不符时返回空字符串
+func (c *CacheServer) cachedSHA256(name string, size int64) string {
+	info, err := os.Stat(filepath.Join(c.Dir, name))
+	if err != nil || info.IsDir() || (size > 0 && info.Size() != size) {
+		return ""
+	}
+	c.hashMu.Lock()
+	defer c.hashMu.Unlock()
+	return c.hashLocked(name, info)
+}
+
+// hashLocked 返回缓存文件的 SHA256，文件未变化时沿用已计算的结果，调用方需持有 hashMu
+func (c *CacheServer) hashLocked(name string, info os.FileInfo) string {
+	cached, ok := c.hashes[name]
+	if !ok || cached.size != info.Size() || !cached.modTime.Equal(info.ModTime()) {
+		sha, err := fileSHA256(filepath.Join(c.Dir, name))
+		if err != nil {
+			return ""
+		}
+		cached = fileHash{size: info.Size(), modTime: info.ModTime(), sha256: sha}
+		c.hashes[name] = cached
+	}
+	return cached.sha256
+}
+
+// packageList 返回已校验安装包的 SHA256 列表
+func (c *CacheServer) packageList() []string {
+	var list []string
+	for sha := range c.verifiedPackages() {
+		list = append(list, sha)
+	}
+	return list
+}
+
+// handlePeerFile 按 SHA256 提供已缓存的安装包，供其他节点下载（只提供已有的文件，不从上游下载）
+func (c *CacheServer) handlePeerFile(w http.ResponseWriter, r *http.Request) {
+	servePeerFile(w, r, c.verifiedPackages())
+}
+
+// servePeerFile 按请求路径 /peer/<SHA256> 提供 files（SHA256 -> 本地路径）中的安装包
+func servePeerFile(w http.ResponseWriter, r *http.Request, files map[string]string) {
+	sha := strings.ToLower(strings.TrimPrefix(r.URL.Path, "/peer/"))
+	local, ok := files[sha]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	file, err := os.Open(local)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.ServeContent(w, r, filepath.Base(local), info.ModTime(), file)
+}
+
+// peerShare 更新过程中向局域网节点共享已按 SHA256 校验的暂存安装包
+// 只在进程运行期间应答，退出后暂存目录中的文件不再共享
+type peerShare struct {
+	udp    *net.UDPConn
+	ln     net.Listener
+	mu     sync.Mutex
+	shared map[string]string // SHA256 -> 本地路径
+}
+
+// startPeerShare 在 udpPort 上应答节点发现，并在随机 TCP 端口提供 /peer/<SHA256> 下载
+func startPeerShare(udpPort int) (*peerShare, error) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{Port: udpPort})
+	if err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("tcp4", ":0")
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	p := &peerShare{udp: conn, ln: ln, shared: make(map[string]string)}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/peer/", func(w http.ResponseWriter, r *http.Request) {
+		servePeerFile(w, r, p.files())
+	})
+	go http.Serve(ln, mux)
+	go answerPeers(conn, ln.Addr().(*net.TCPAddr).Port, p.packageList)
+	return p, nil
+}
+
+// add 共享已按 sha256Hex 校验的安装包，p 为 nil 或 sha256Hex 为空时忽略
+func (p *peerShare) add(sha256Hex, path string) {
+	if p == nil || sha256Hex == "" {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.shared[strings.ToLower(sha256Hex)] = path
+}
+
+// remove 停止共享 path 处的安装包，p 为 nil 时忽略
+func (p *peerShare) remove(path string) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for sha, shared := range p.shared {
+		if shared == path {
+			delete(p.shared, sha)
+		}
+	}
+}
+
+// files 返回共享的安装包，SHA256 -> 本地路径
+func (p *peerShare) files() map[string]string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	files := make(map[string]string, len(p.shared))
+	for sha, path := range p.shared {
+		files[sha] = path
+	}
+	return files
+}
+
+// packageList 返回共享的安装包 SHA256 列表
+func (p *peerShare) packageList() []string {
+	var list []string
+	for sha := range p.files() {
+		list = append(list, sha)
+	}
+	return list
+}
+
+// Close 停止共享
+func (p *peerShare) Close() {
+	if p == nil {
+		return
+	}
+	p.udp.Close()
+	p.ln.Close()
+}
+
+// sharePeers 开启节点发现时启动暂存安装包的共享，未开启或端口被占用（如本机运行着 serve）时返回 nil
+func (s *session) sharePeers() *peerShare {
+	if !s.cfg.PeerDiscovery {
+		return nil
+	}
+	share, err := startPeerShare(peerDiscoveryPort)
+	if err != nil {
+		s.warn(fmt.Sprintf("局域网节点共享未启动: %v", err))
+		return nil
+	}
+	return share
+}
@@ -0,0 +1,114 @@
+package internal
+
+import (
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startTestPeerShare 在随机 UDP 端口启动共享，返回共享和发现查询的目标地址
+func startTestPeerShare(t *testing.T) (*peerShare, []*net.UDPAddr) {
+	t.Helper()
+	share, err := startPeerShare(0)
+	if err != nil {
+		t.Skipf("无法监听本地端口: %v", err)
+	}
+	t.Cleanup(share.Close)
+	port := share.udp.LocalAddr().(*net.UDPAddr).Port
+	return share, []*net.UDPAddr{{IP: net.IPv4(127, 0, 0, 1), Port: port}}
+}
+
+func TestPeerShareLoopback(t *testing.T) {
+	share, targets := startTestPeerShare(t)
+	dir := t.TempDir()
+	pkg := filepath.Join(dir, "chrome_installer.exe")
+	content := strings.Repeat("chrome installer ", 4096)
+	writeTestFile(t, pkg, content)
+	sha := sha256Hex(content)
+	share.add(strings.ToUpper(sha), pkg)
+
+	// 伪造的节点：声称持有 evil 的 SHA256，实际提供其他内容
+	evil := sha256Hex("expected content")
+	share.add(evil, pkg)
+
+	peers, err := DiscoverPeers(targets, 500*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(peers) != 1 || !strings.HasPrefix(peers[0].Addr, "127.0.0.1:") {
+		t.Fatalf("peers = %+v", peers)
+	}
+
+	urls := PeerURLs(peers, sha)
+	if len(urls) != 1 {
+		t.Fatalf("PeerURLs = %v", urls)
+	}
+	dest := filepath.Join(dir, "downloaded.exe")
+	if err := DownloadVerified(urls, dest, int64(len(content)), sha, 2, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := readTestFile(t, dest); got != content {
+		t.Fatal("从节点下载的内容不一致")
+	}
+
+	// 节点提供的内容与 SHA256 不符时拒绝
+	tampered := filepath.Join(dir, "tampered.exe")
+	err = DownloadVerified(PeerURLs(peers, evil), tampered, 0, evil, 1, nil)
+	if err == nil || !strings.Contains(err.Error(), "SHA256 校验失败") {
+		t.Fatalf("err = %v, want SHA256 校验失败", err)
+	}
+	if fileExists(tampered) {
+		t.Fatal("校验失败时不应保存文件")
+	}
+
+	if urls := PeerURLs(peers, sha256Hex("unknown")); len(urls) != 0 {
+		t.Fatalf("未持有的安装包 PeerURLs = %v", urls)
+	}
+}
+
+func TestStagePlanSharesVerifiedPackages(t *testing.T) {
+	share, targets := startTestPeerShare(t)
+	setupTestInstall(t, nil)
+	cfg := loadTestConfig(t)
+
+	latest, err := NewMetadataClient(cfg).GetLatestVersion(cfg.Channel)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan := BuildUpdatePlan(cfg, ReadInstallState(cfg), latest, PlanOptions{Now: time.Now()})
+	packages, err := stagePlan(cfg, plan, true, share)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	peers, err := DiscoverPeers(targets, 500*time.Millisecond)
+	if err != nil || len(peers) != 1 {
+		t.Fatalf("peers = %+v, err = %v", peers, err)
+	}
+	for _, d := range plan.Downloads {
+		if len(PeerURLs(peers, d.SHA256)) != 1 {
+			t.Fatalf("%s 安装包应共享, packages = %v", d.Component, peers[0].Packages)
+		}
+	}
+
+	// 安装后先停止共享再删除暂存文件
+	if err := applyPlan(cfg, plan, packages, share); err != nil {
+		t.Fatal(err)
+	}
+	if fileExists(packages[ComponentChrome]) {
+		t.Fatal("安装后应删除暂存文件")
+	}
+	peers, err = DiscoverPeers(targets, 500*time.Millisecond)
+	if err != nil || len(peers) != 1 {
+		t.Fatalf("peers = %+v, err = %v", peers, err)
+	}
+	if len(peers[0].Packages) != 0 {
+		t.Fatalf("安装后不应再提供暂存的安装包, packages = %v", peers[0].Packages)
+	}
+	dest := filepath.Join(t.TempDir(), "chrome.exe")
+	if err := DownloadVerified([]string{peers[0].URL(latest.ChromeSHA256)}, dest, 0, latest.ChromeSHA256, 1, nil); err == nil {
+		t.Fatal("停止共享后下载应失败")
+	}
+}
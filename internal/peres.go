@@ -0,0 +1,304 @@
+package internal
+
+import (
+	"bytes"
+	"debug/pe"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+)
+
+// mini_installer 的资源类型
+const (
+	resourcePacked       = "B7" // 7z 压缩包（CHROME.PACKED.7Z / CHROME.7Z）
+	resourceCompressed   = "BL" // CAB 压缩的文件（如 SETUP.EX_）
+	resourceUncompressed = "BN" // 未压缩的文件
+)
+
+// maxResourceDepth 资源目录固定为 类型/名称/语言 三层
+const maxResourceDepth = 3
+
+// PEResource PE 文件中的资源
+type PEResource struct {
+	Type string // 资源类型，数字 ID 表示为 "#<ID>"
+	Name string // 资源名称，数字 ID 表示为 "#<ID>"
+	Lang uint32
+	Size int64
+
+	section *pe.Section
+	offset  int64 // 资源数据在节中的偏移
+}
+
+// Open 返回资源数据
+func (r PEResource) Open() io.Reader {
+	return io.NewSectionReader(r.section, r.offset, r.Size)
+}
+
+// ReadPEResources 读取 PE 文件的资源列表
+func ReadPEResources(f *pe.File) ([]PEResource, error) {
+	var dir pe.DataDirectory
+	switch oh := f.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		if oh.NumberOfRvaAndSizes > pe.IMAGE_DIRECTORY_ENTRY_RESOURCE {
+			dir = oh.DataDirectory[pe.IMAGE_DIRECTORY_ENTRY_RESOURCE]
+		}
+	case *pe.OptionalHeader64:
+		if oh.NumberOfRvaAndSizes > pe.IMAGE_DIRECTORY_ENTRY_RESOURCE {
+			dir = oh.DataDirectory[pe.IMAGE_DIRECTORY_ENTRY_RESOURCE]
+		}
+	}
+	if dir.VirtualAddress == 0 {
+		return nil, fmt.Errorf("PE 文件没有资源")
+	}
+	root := sectionAt(f, dir.VirtualAddress)
+	if root == nil {
+		return nil, fmt.Errorf("资源目录不在任何节中")
+	}
+
+	w := &resourceWalker{file: f, root: root, base: int64(dir.VirtualAddress - root.VirtualAddress)}
+	if err := w.walk(0, nil); err != nil {
+		return nil, fmt.Errorf("读取资源目录失败: %w", err)
+	}
+	return w.resources, nil
+}
+
+// sectionAt 返回包含指定 RVA 的节
+func sectionAt(f *pe.File, rva uint32) *pe.Section {
+	for _, s := range f.Sections {
+		if rva >= s.VirtualAddress && rva < s.VirtualAddress+s.Size {
+			return s
+		}
+	}
+	return nil
+}
+
+// resourceWalker 遍历资源目录树
+type resourceWalker struct {
+	file      *pe.File
+	root      *pe.Section
+	base      int64 // 资源目录在节中的偏移，目录中的偏移都相对于此
+	resources []PEResource
+}
+
+// walk 遍历 offset 处的目录，path 为已经过的 类型/名称 层
+func (w *resourceWalker) walk(offset uint32, path []string) error {
+	if len(path) >= maxResourceDepth {
+		return fmt.Errorf("资源目录层级过深")
+	}
+	var header [16]byte
+	if _, err := w.root.ReadAt(header[:], w.base+int64(offset)); err != nil {
+		return err
+	}
+	count := int(binary.LittleEndian.Uint16(header[12:])) + int(binary.LittleEndian.Uint16(header[14:]))
+
+	entries := make([]byte, count*8)
+	if _, err := w.root.ReadAt(entries, w.base+int64(offset)+16); err != nil {
+		return err
+	}
+	for i := 0; i < count; i++ {
+		nameField := binary.LittleEndian.Uint32(entries[i*8:])
+		dataField := binary.LittleEndian.Uint32(entries[i*8+4:])
+
+		name, err := w.entryName(nameField)
+		if err != nil {
+			return err
+		}
+		if dataField&0x80000000 != 0 {
+			if err := w.walk(dataField&0x7fffffff, append(path, name)); err != nil {
+				return err
+			}
+			continue
+		}
+		if len(path) != maxResourceDepth-1 {
+			// 非标准层级的资源，忽略
+			continue
+		}
+		if err := w.addData(dataField, path, nameField); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// entryName 返回目录项的名称，数字 ID 表示为 "#<ID>"
+func (w *resourceWalker) entryName(field uint32) (string, error) {
+	if field&0x80000000 == 0 {
+		return fmt.Sprintf("#%d", field), nil
+	}
+	offset := w.base + int64(field&0x7fffffff)
+	var length [2]byte
+	if _, err := w.root.ReadAt(length[:], offset); err != nil {
+		return "", err
+	}
+	raw := make([]byte, int(binary.LittleEndian.Uint16(length[:]))*2)
+	if _, err := w.root.ReadAt(raw, offset+2); err != nil {
+		return "", err
+	}
+	chars := make([]uint16, len(raw)/2)
+	for i := range chars {
+		chars[i] = binary.LittleEndian.Uint16(raw[i*2:])
+	}
+	return string(utf16.Decode(chars)), nil
+}
+
+// addData 记录资源数据项
+func (w *resourceWalker) addData(offset uint32, path []string, lang uint32) error {
+	var entry [16]byte
+	if _, err := w.root.ReadAt(entry[:], w.base+int64(offset)); err != nil {
+		return err
+	}
+	rva := binary.LittleEndian.Uint32(entry[0:])
+	size := binary.LittleEndian.Uint32(entry[4:])
+
+	section := sectionAt(w.file, rva)
+	if section == nil {
+		return fmt.Errorf("资源 %s/%s 的数据不在任何节中", path[0], path[1])
+	}
+	sectionOffset := int64(rva - section.VirtualAddress)
+	if sectionOffset+int64(size) > int64(section.Size) {
+		return fmt.Errorf("资源 %s/%s 的数据超出节的范围", path[0], path[1])
+	}
+	w.resources = append(w.resources, PEResource{
+		Type:    path[0],
+		Name:    path[1],
+		Lang:    lang,
+		Size:    int64(size),
+		section: section,
+		offset:  sectionOffset,
+	})
+	return nil
+}
+
+// findChromePayload 在 mini_installer 的资源中查找 Chrome 安装数据
+// 优先使用 7z 资源（CHROME.PACKED.7Z / CHROME.7Z），其次是压缩或未压缩的 chrome.7z
+func findChromePayload(resources []PEResource) (PEResource, bool) {
+	for _, typ := range []string{resourcePacked, resourceCompressed, resourceUncompressed} {
+		for _, r := range resources {
+			if strings.EqualFold(r.Type, typ) && strings.HasPrefix(strings.ToUpper(r.Name), "CHROME") {
+				return r, true
+			}
+		}
+	}
+	return PEResource{}, false
+}
+
+// ExtractInstallerPayload 从 Chrome 离线安装程序（mini_installer）的资源中解压 Chrome 文件到指定目录
+// 无需外部 7-Zip：7z 资源（B7）和未压缩资源（BN）直接解压，CAB 压缩的资源（BL）先解压出 chrome.7z，嵌套的 chrome.7z 继续解压
+// 仅支持 B7、BN 和未压缩或 MSZIP 压缩的 BL 资源；LZX 压缩的 BL 资源返回错误，由调用方按 7z 自解压程序处理（需要外部 7-Zip）
+func ExtractInstallerPayload(installerPath, destDir string, progress ExtractProgress) error {
+	f, err := pe.Open(installerPath)
+	if err != nil {
+		return fmt.Errorf("读取安装程序失败: %w", err)
+	}
+	defer f.Close()
+
+	resources, err := ReadPEResources(f)
+	if err != nil {
+		return err
+	}
+	payload, ok := findChromePayload(resources)
+	if !ok {
+		return fmt.Errorf("安装程序中没有 Chrome 安装数据")
+	}
+
+	if err := os.MkdirAll(destDir, 0755); err != nil {
+		return fmt.Errorf("创建目标目录失败: %w", err)
+	}
+
+	// 先将资源写出为文件，7z 需要随机读取
+	var archives []string
+	switch strings.ToUpper(payload.Type) {
+	case resourceCompressed:
+		archives, err = extractCab(payload.Open(), destDir)
+	default:
+		var path string
+		path, err = writeResource(payload, destDir)
+		archives = []string{path}
+	}
+	if errors.Is(err, errCabLZX) {
+		return fmt.Errorf("资源 %s 为 LZX 压缩，内置读取器仅支持 B7、BN 和未压缩或 MSZIP 压缩的 BL 资源", payload.Name)
+	}
+	if err != nil {
+		return fmt.Errorf("解压资源 %s 失败: %w", payload.Name, err)
+	}
+
+	// CHROME.PACKED.7Z 中是 chrome.7z，chrome.7z 中是 Chrome-bin
+	for depth := 0; len(archives) > 0; depth++ {
+		if depth >= 2 {
+			return fmt.Errorf("安装数据嵌套过深")
+		}
+		var nested []string
+		for _, archive := range archives {
+			if !strings.EqualFold(filepath.Ext(archive), ".7z") {
+				continue
+			}
+			before := listFiles(destDir)
+			err := extract7zPureGo(archive, destDir, progress)
+			os.Remove(archive)
+			if err != nil {
+				return fmt.Errorf("解压 %s 失败: %w", filepath.Base(archive), err)
+			}
+			for name := range listFiles(destDir) {
+				if !before[name] && strings.EqualFold(filepath.Ext(name), ".7z") {
+					nested = append(nested, filepath.Join(destDir, name))
+				}
+			}
+		}
+		archives = nested
+	}
+	return nil
+}
+
+// writeResource 将资源写出到目录，文件名为小写的资源名称
+func writeResource(r PEResource, dir string) (string, error) {
+	path := filepath.Join(dir, resourceFileName(r.Name))
+	out, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer out.Close()
+	if _, err := io.Copy(out, r.Open()); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// resourceFileName 将资源名称或 CAB 中的文件名转换为安全的文件名
+func resourceFileName(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	if name == "." || name == ".." || name == "/" {
+		name = "payload"
+	}
+	return strings.ToLower(name)
+}
+
+// listFiles 返回目录中的文件名（不含子目录）
+func listFiles(dir string) map[string]bool {
+	names := make(map[string]bool)
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			names[entry.Name()] = true
+		}
+	}
+	return names
+}
+
+// isPEFile 文件是否为 PE 可执行文件
+func isPEFile(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+	header := make([]byte, len(magicPE))
+	if _, err := io.ReadFull(file, header); err != nil {
+		return false
+	}
+	return bytes.Equal(header, magicPE)
+}
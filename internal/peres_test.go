@@ -0,0 +1,399 @@
+package internal
+
+import (
+	"bytes"
+	"debug/pe"
+	"encoding/binary"
+	"hash/crc32"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// 测试 PE 文件中资源节的位置
+const (
+	testRsrcRVA    = 0x1000
+	testRsrcOffset = 0x200
+)
+
+// testFile 测试压缩包中的文件
+type testFile struct {
+	name string
+	body []byte
+}
+
+// build7z 构造使用 Copy（不压缩）方式的 7z 压缩包，所有文件位于同一个数据段
+func build7z(files []testFile) []byte {
+	var packed, header bytes.Buffer
+	for _, f := range files {
+		packed.Write(f.body)
+	}
+
+	header.WriteByte(0x01) // Header
+	header.WriteByte(0x04) // MainStreamsInfo
+
+	header.WriteByte(0x06) // PackInfo
+	write7zNumber(&header, 0)
+	write7zNumber(&header, 1)
+	header.WriteByte(0x09) // Size
+	write7zNumber(&header, uint64(packed.Len()))
+	header.WriteByte(0x00)
+
+	header.WriteByte(0x07) // UnPackInfo
+	header.WriteByte(0x0B) // Folder
+	write7zNumber(&header, 1)
+	header.WriteByte(0x00)                 // 非外部
+	header.Write([]byte{0x01, 0x01, 0x00}) // 1 个编码器，ID 长度 1，Copy
+	header.WriteByte(0x0C)                 // CodersUnPackSize
+	write7zNumber(&header, uint64(packed.Len()))
+	header.WriteByte(0x00)
+
+	header.WriteByte(0x08) // SubStreamsInfo
+	header.WriteByte(0x0D) // NumUnPackStream
+	write7zNumber(&header, uint64(len(files)))
+	header.WriteByte(0x09) // Size，最后一个文件的大小由数据段大小推算
+	for _, f := range files[:len(files)-1] {
+		write7zNumber(&header, uint64(len(f.body)))
+	}
+	header.Write([]byte{0x0A, 0x01}) // CRC，全部已定义
+	for _, f := range files {
+		binary.Write(&header, binary.LittleEndian, crc32.ChecksumIEEE(f.body))
+	}
+	header.WriteByte(0x00)
+	header.WriteByte(0x00) // MainStreamsInfo 结束
+
+	header.WriteByte(0x05) // FilesInfo
+	write7zNumber(&header, uint64(len(files)))
+	var names bytes.Buffer
+	names.WriteByte(0x00) // 非外部
+	for _, f := range files {
+		for _, c := range utf16.Encode([]rune(f.name)) {
+			binary.Write(&names, binary.LittleEndian, c)
+		}
+		names.Write([]byte{0, 0})
+	}
+	header.WriteByte(0x11) // Name
+	write7zNumber(&header, uint64(names.Len()))
+	header.Write(names.Bytes())
+	header.WriteByte(0x00) // FilesInfo 结束
+	header.WriteByte(0x00) // Header 结束
+
+	start := make([]byte, 20)
+	binary.LittleEndian.PutUint64(start[0:], uint64(packed.Len()))
+	binary.LittleEndian.PutUint64(start[8:], uint64(header.Len()))
+	binary.LittleEndian.PutUint32(start[16:], crc32.ChecksumIEEE(header.Bytes()))
+
+	var out bytes.Buffer
+	out.Write(magic7z)
+	out.Write([]byte{0x00, 0x04})
+	binary.Write(&out, binary.LittleEndian, crc32.ChecksumIEEE(start))
+	out.Write(start)
+	out.Write(packed.Bytes())
+	out.Write(header.Bytes())
+	return out.Bytes()
+}
+
+// write7zNumber 按 7z 的变长格式写入数字
+func write7zNumber(b *bytes.Buffer, v uint64) {
+	switch {
+	case v < 0x80:
+		b.WriteByte(byte(v))
+	case v < 0x4000:
+		b.Write([]byte{0x80 | byte(v>>8), byte(v)})
+	default:
+		b.WriteByte(0xFF)
+		binary.Write(b, binary.LittleEndian, v)
+	}
+}
+
+// testResource 测试 PE 文件中的资源
+type testResource struct {
+	typ, name string
+	data      []byte
+	loop      bool // 语言层的目录项指向自身，构造超出层级的目录
+}
+
+// buildTestPE 构造只有一个资源节的 PE 文件，资源目录为 类型/名称/语言 三层
+// 资源目录位于文件偏移 testRsrcOffset 处
+func buildTestPE(resources []testResource) []byte {
+	type layout struct{ nameDir, langDir, dataEntry, typeStr, nameStr, data int }
+	lay := make([]layout, len(resources))
+	off := 16 + 8*len(resources)
+	for i := range resources {
+		lay[i].nameDir, lay[i].langDir, lay[i].dataEntry = off, off+24, off+48
+		off += 64
+	}
+	for i, r := range resources {
+		lay[i].typeStr = off
+		off += 2 + 2*len(utf16.Encode([]rune(r.typ)))
+		lay[i].nameStr = off
+		off += 2 + 2*len(utf16.Encode([]rune(r.name)))
+	}
+	for i, r := range resources {
+		off = (off + 7) &^ 7
+		lay[i].data = off
+		off += len(r.data)
+	}
+
+	rsrc := make([]byte, off)
+	le := binary.LittleEndian
+	putEntry := func(at int, name, data uint32) {
+		le.PutUint32(rsrc[at:], name)
+		le.PutUint32(rsrc[at+4:], data)
+	}
+	putString := func(at int, s string) {
+		chars := utf16.Encode([]rune(s))
+		le.PutUint16(rsrc[at:], uint16(len(chars)))
+		for i, c := range chars {
+			le.PutUint16(rsrc[at+2+2*i:], c)
+		}
+	}
+
+	le.PutUint16(rsrc[12:], uint16(len(resources))) // 按名称索引的类型
+	for i, r := range resources {
+		l := lay[i]
+		putString(l.typeStr, r.typ)
+		putString(l.nameStr, r.name)
+		putEntry(16+8*i, 0x80000000|uint32(l.typeStr), 0x80000000|uint32(l.nameDir))
+
+		le.PutUint16(rsrc[l.nameDir+12:], 1)
+		putEntry(l.nameDir+16, 0x80000000|uint32(l.nameStr), 0x80000000|uint32(l.langDir))
+
+		le.PutUint16(rsrc[l.langDir+14:], 1) // 按 ID 索引的语言
+		langData := uint32(l.dataEntry)
+		if r.loop {
+			langData = 0x80000000 | uint32(l.langDir)
+		}
+		putEntry(l.langDir+16, 1033, langData)
+
+		le.PutUint32(rsrc[l.dataEntry:], testRsrcRVA+uint32(l.data))
+		le.PutUint32(rsrc[l.dataEntry+4:], uint32(len(r.data)))
+		copy(rsrc[l.data:], r.data)
+	}
+
+	var out bytes.Buffer
+	dos := make([]byte, 0x40)
+	copy(dos, magicPE)
+	le.PutUint32(dos[0x3c:], 0x40)
+	out.Write(dos)
+	out.WriteString("PE\x00\x00")
+
+	oh := pe.OptionalHeader64{
+		Magic:               0x20b,
+		SectionAlignment:    0x1000,
+		FileAlignment:       0x200,
+		SizeOfImage:         testRsrcRVA + uint32(len(rsrc)),
+		SizeOfHeaders:       testRsrcOffset,
+		NumberOfRvaAndSizes: 16,
+	}
+	oh.DataDirectory[pe.IMAGE_DIRECTORY_ENTRY_RESOURCE] = pe.DataDirectory{VirtualAddress: testRsrcRVA, Size: uint32(len(rsrc))}
+	binary.Write(&out, le, pe.FileHeader{
+		Machine:              pe.IMAGE_FILE_MACHINE_AMD64,
+		NumberOfSections:     1,
+		SizeOfOptionalHeader: uint16(binary.Size(oh)),
+		Characteristics:      pe.IMAGE_FILE_EXECUTABLE_IMAGE,
+	})
+	binary.Write(&out, le, oh)
+
+	section := pe.SectionHeader32{
+		VirtualSize:      uint32(len(rsrc)),
+		VirtualAddress:   testRsrcRVA,
+		SizeOfRawData:    uint32(len(rsrc)),
+		PointerToRawData: testRsrcOffset,
+	}
+	copy(section.Name[:], ".rsrc")
+	binary.Write(&out, le, section)
+	out.Write(make([]byte, testRsrcOffset-out.Len()))
+	out.Write(rsrc)
+	return out.Bytes()
+}
+
+// chromeArchive 返回包含 Chrome-bin 的 chrome.7z
+func chromeArchive() []byte {
+	return build7z([]testFile{
+		{name: "Chrome-bin/chrome.exe", body: []byte("chrome")},
+		{name: "Chrome-bin/121.0.1.0/chrome.dll", body: bytes.Repeat([]byte("dll"), 100)},
+	})
+}
+
+func TestExtractInstallerPayload(t *testing.T) {
+	packed := build7z([]testFile{{name: "chrome.7z", body: chromeArchive()}})
+	setup := testResource{typ: "BL", name: "SETUP.EX_", data: buildTestCab(cabCompressMSZIP, []cabTestFile{{name: "setup.exe", body: []byte("setup")}})}
+
+	tests := []struct {
+		name      string
+		resources []testResource
+	}{
+		{
+			name:      "B7 资源",
+			resources: []testResource{setup, {typ: "B7", name: "CHROME.PACKED.7Z", data: packed}},
+		},
+		{
+			name: "MSZIP 压缩的 BL 资源",
+			resources: []testResource{setup, {typ: "BL", name: "CHROME.7Z", data: buildTestCab(cabCompressMSZIP, []cabTestFile{
+				{name: "chrome.7z", body: chromeArchive()},
+			})}},
+		},
+		{
+			name:      "BN 资源",
+			resources: []testResource{setup, {typ: "BN", name: "CHROME.7Z", data: chromeArchive()}},
+		},
+		{
+			name: "优先使用 B7 资源",
+			resources: []testResource{
+				{typ: "BN", name: "CHROME.7Z", data: []byte("not used")},
+				{typ: "B7", name: "CHROME.PACKED.7Z", data: packed},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			installer := filepath.Join(dir, "mini_installer.exe")
+			writeTestFile(t, installer, string(buildTestPE(tt.resources)))
+			if !isPEFile(installer) {
+				t.Fatal("构造的文件不是 PE 文件")
+			}
+
+			dest := filepath.Join(dir, "dest")
+			if err := ExtractInstallerPayload(installer, dest, nil); err != nil {
+				t.Fatal(err)
+			}
+			if got := readTestFile(t, filepath.Join(dest, "Chrome-bin", "chrome.exe")); got != "chrome" {
+				t.Fatalf("chrome.exe = %q", got)
+			}
+			if got := readTestFile(t, filepath.Join(dest, "Chrome-bin", "121.0.1.0", "chrome.dll")); got != strings.Repeat("dll", 100) {
+				t.Fatalf("chrome.dll = %q", got)
+			}
+			if files := listFiles(dest); len(files) != 0 {
+				t.Fatalf("解压后应删除中间的压缩包: %v", files)
+			}
+		})
+	}
+}
+
+func TestExtractInstallerPayloadErrors(t *testing.T) {
+	// 三层嵌套的 7z 超出安装数据的嵌套层数
+	nested := build7z([]testFile{{name: "chrome.7z", body: build7z([]testFile{{name: "more.7z", body: chromeArchive()}})}})
+
+	tests := []struct {
+		name      string
+		resources []testResource
+		wantErr   string
+	}{
+		{
+			name:      "没有 Chrome 安装数据",
+			resources: []testResource{{typ: "BN", name: "SETUP.EXE", data: []byte("setup")}},
+			wantErr:   "没有 Chrome 安装数据",
+		},
+		{
+			name:      "嵌套过深",
+			resources: []testResource{{typ: "B7", name: "CHROME.PACKED.7Z", data: nested}},
+			wantErr:   "嵌套过深",
+		},
+		{
+			name:      "LZX 压缩",
+			resources: []testResource{{typ: "BL", name: "CHROME.7Z", data: buildTestCab(cabCompressLZX, []cabTestFile{{name: "chrome.7z", body: []byte("x")}})}},
+			wantErr:   "仅支持 B7、BN 和未压缩或 MSZIP 压缩的 BL 资源",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			installer := filepath.Join(dir, "mini_installer.exe")
+			writeTestFile(t, installer, string(buildTestPE(tt.resources)))
+			err := ExtractInstallerPayload(installer, filepath.Join(dir, "dest"), nil)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadPEResources(t *testing.T) {
+	resources := []testResource{
+		{typ: "BL", name: "SETUP.EX_", data: []byte("setup")},
+		{typ: "B7", name: "CHROME.PACKED.7Z", data: []byte("payload")},
+	}
+
+	tests := []struct {
+		name    string
+		data    func() []byte
+		wantErr string
+	}{
+		{name: "正常", data: func() []byte { return buildTestPE(resources) }},
+		{
+			name: "目录项数量超出节的范围",
+			data: func() []byte {
+				data := buildTestPE(resources)
+				binary.LittleEndian.PutUint16(data[testRsrcOffset+14:], 0xFFFF)
+				return data
+			},
+			wantErr: "读取资源目录失败",
+		},
+		{
+			name: "资源数据超出节的范围",
+			data: func() []byte {
+				data := buildTestPE(resources[:1])
+				// 唯一资源的数据项位于根目录项和两层目录之后
+				entry := testRsrcOffset + 16 + 8 + 48
+				binary.LittleEndian.PutUint32(data[entry+4:], 0x10000)
+				return data
+			},
+			wantErr: "超出节的范围",
+		},
+		{
+			name:    "目录层级过深",
+			data:    func() []byte { return buildTestPE([]testResource{{typ: "B7", name: "CHROME.7Z", loop: true}}) },
+			wantErr: "资源目录层级过深",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := pe.NewFile(bytes.NewReader(tt.data()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ReadPEResources(f)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != 2 || got[1].Type != "B7" || got[1].Name != "CHROME.PACKED.7Z" || got[1].Lang != 1033 {
+				t.Fatalf("resources = %+v", got)
+			}
+			payload, ok := findChromePayload(got)
+			if !ok || payload.Name != "CHROME.PACKED.7Z" {
+				t.Fatalf("findChromePayload = %+v, %v", payload, ok)
+			}
+			var buf bytes.Buffer
+			buf.ReadFrom(payload.Open())
+			if buf.String() != "payload" {
+				t.Fatalf("资源数据 = %q", buf.String())
+			}
+		})
+	}
+}
+
+func TestIsPEFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "file")
+	for content, want := range map[string]bool{"MZ\x90\x00": true, "PK\x03\x04": false, "M": false} {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if got := isPEFile(path); got != want {
+			t.Fatalf("isPEFile(%q) = %v, want %v", content, got, want)
+		}
+	}
+}
@@ -0,0 +1,478 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// 组件名称
+const (
+	ComponentChrome     = "chrome"
+	ComponentChromePlus = "chrome_plus"
+)
+
+// versionDirRegex Chrome 版本目录名（如 123.0.6312.86）
+var versionDirRegex = regexp.MustCompile(`^\d+\.\d+\.\d+\.\d+$`)
+
+// InstallState 磁盘上的安装状态
+type InstallState struct {
+	ChromeExists     bool     // chrome.exe 是否存在
+	ChromePlusExists bool     // version.dll 是否存在
+	VersionDirs      []string // App 目录下的 Chrome 版本目录
+}
+
+// ComponentChange 组件版本变更
+type ComponentChange struct {
+	Component string `json:"component"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Install   bool   `json:"install,omitempty"` // 组件未安装，首次安装
+	Urgent    string `json:"urgent,omitempty"`  // 紧急更新的原因（超过策略阈值，不允许跳过）
+	NoSkip    bool   `json:"no_skip,omitempty"` // 团队策略不允许跳过此版本
+	Reason    string `json:"reason,omitempty"`  // 暂不更新的原因（仅 Held 中使用）
+}
+
+// PlannedDownload 计划下载的文件
+type PlannedDownload struct {
+	Component string   `json:"component"`
+	URLs      []string `json:"urls"`
+	Size      int64    `json:"size"`             // 文件大小，未知时为 0
+	SHA256    string   `json:"sha256,omitempty"` // 期望的 SHA256，未知时为空
+	File      string   `json:"file"`             // 暂存目录中的文件名
+}
+
+// UpdatePlan 更新计划
+// 由配置、磁盘状态和最新版本信息计算得出，不包含任何副作用，执行由 executePlan 完成
+type UpdatePlan struct {
+	Channel   string            `json:"channel"`
+	AppDir    string            `json:"app_dir"`   // Chrome 程序目录（相对程序目录）
+	Changes   []ComponentChange `json:"changes"`   // 需要更新的组件
+	Held      []ComponentChange `json:"held"`      // 有新版本但暂不更新的组件
+	Downloads []PlannedDownload `json:"downloads"` // 需要下载的文件
+	Replace   []string          `json:"replace"`   // 将被替换的文件（相对程序目录）
+	Cleanup   []string          `json:"cleanup"`   // 更新后将清理的旧版本目录（相对程序目录）
+}
+
+// PlanOptions 计划计算选项
+type PlanOptions struct {
+	IgnoreSkipped    bool        // 忽略已跳过和暂停提醒的版本（静默模式、手动更新）
+	IgnoreSoak       bool        // 忽略 Chrome 新版本的最短发布时长（手动更新）
+	ChromeReleasedAt time.Time   // 最新 Chrome 版本的发布时间，零值表示未知
+	Team             *TeamPolicy // 团队策略，为 nil 表示不使用
+	Now              time.Time   // 当前时间，零值表示 time.Now()
+}
+
+// Empty 计划是否没有任何更新
+func (p *UpdatePlan) Empty() bool {
+	return len(p.Changes) == 0
+}
+
+// Urgent 计划中是否包含紧急更新
+func (p *UpdatePlan) Urgent() bool {
+	for _, c := range p.Changes {
+		if c.Urgent != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Change 获取指定组件的变更，不更新时返回 nil
+func (p *UpdatePlan) Change(component string) *ComponentChange {
+	for i := range p.Changes {
+		if p.Changes[i].Component == component {
+			return &p.Changes[i]
+		}
+	}
+	return nil
+}
+
+// Download 获取指定组件的下载项，不下载时返回 nil
+func (p *UpdatePlan) Download(component string) *PlannedDownload {
+	for i := range p.Downloads {
+		if p.Downloads[i].Component == component {
+			return &p.Downloads[i]
+		}
+	}
+	return nil
+}
+
+// ReadInstallState 读取磁盘上的安装状态
+func ReadInstallState(cfg *Config) InstallState {
+	state := InstallState{
+		ChromeExists:     fileExists(cfg.GetChromePath()),
+		ChromePlusExists: fileExists(cfg.GetChromePlusDllPath()),
+	}
+
+	entries, err := os.ReadDir(cfg.GetAppDir())
+	if err != nil {
+		return state
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && versionDirRegex.MatchString(entry.Name()) {
+			state.VersionDirs = append(state.VersionDirs, entry.Name())
+		}
+	}
+	return state
+}
+
+// BuildUpdatePlan 根据配置、安装状态和最新版本计算更新计划
+func BuildUpdatePlan(cfg *Config, state InstallState, latest *VersionInfo, opts PlanOptions) *UpdatePlan {
+	plan := &UpdatePlan{
+		Channel:   cfg.Channel,
+		AppDir:    cfg.ChromePath,
+		Changes:   []ComponentChange{},
+		Held:      []ComponentChange{},
+		Downloads: []PlannedDownload{},
+		Replace:   []string{},
+		Cleanup:   []string{},
+	}
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+
+	// 判断 Chrome 是否需要更新
+	chrome := ComponentChange{Component: ComponentChrome, From: cfg.Version, To: latest.ChromeVersion}
+	chrome.Urgent = urgency(cfg, ComponentChrome, cfg.Version, latest.ChromeVersion, opts.Now)
+	if chrome.Urgent == "" {
+		chrome.Urgent = opts.Team.chromeUrgency(cfg.Channel, cfg.Version, opts.Now)
+	}
+	chrome.NoSkip = !opts.Team.allowSkip()
+	if !state.ChromeExists {
+		chrome.Install = true
+		plan.Changes = append(plan.Changes, chrome)
+	} else if cfg.Version == "" {
+		plan.Changes = append(plan.Changes, chrome)
+	} else if CompareVersion(cfg.Version, latest.ChromeVersion) {
+		// 检查团队策略是否批准此版本，以及是否已跳过此版本或暂停提醒
+		if reason := opts.Team.chromeHold(cfg.Channel, latest.ChromeVersion); reason != "" {
+			// 未经批准的版本不更新，也不视为紧急更新
+			chrome.Urgent = ""
+			chrome.Reason = reason
+			plan.Held = append(plan.Held, chrome)
+		} else if reason := holdReason(cfg, chrome, cfg.SkippedChromeVersion, opts); reason != "" {
+			chrome.Reason = reason
+			plan.Held = append(plan.Held, chrome)
+		} else if reason := soakReason(cfg, opts); reason != "" {
+			// 新版本发布时间不足，暂不提供
+			chrome.Reason = reason
+			plan.Held = append(plan.Held, chrome)
+		} else {
+			plan.Changes = append(plan.Changes, chrome)
+		}
+	}
+
+	// 判断 Chrome++ 是否需要更新（未获取到 Chrome++ 信息时不更新，不影响 Chrome）
+	if latest.ChromePlusVersion != "" {
+		plus := ComponentChange{Component: ComponentChromePlus, From: cfg.ChromePlusVersion, To: latest.ChromePlusVersion}
+		plus.Urgent = urgency(cfg, ComponentChromePlus, cfg.ChromePlusVersion, latest.ChromePlusVersion, opts.Now)
+		if plus.Urgent == "" {
+			plus.Urgent = opts.Team.chromePlusUrgency(opts.Now)
+		}
+		plus.NoSkip = !opts.Team.allowSkip()
+		if !state.ChromePlusExists {
+			plus.Install = true
+			// Chrome 已安装时单独安装 Chrome++，可暂停提醒
+			if reason := snoozeReason(cfg, ComponentChromePlus, opts); state.ChromeExists && reason != "" {
+				plus.Reason = reason
+				plan.Held = append(plan.Held, plus)
+			} else {
+				plan.Changes = append(plan.Changes, plus)
+			}
+		} else if cfg.ChromePlusVersion == "" {
+			plan.Changes = append(plan.Changes, plus)
+		} else if !sameTag(cfg.ChromePlusVersion, latest.ChromePlusVersion) {
+			// 检查团队策略是否批准此版本，以及是否已跳过此版本或暂停提醒
+			if reason := opts.Team.chromePlusHold(latest.ChromePlusVersion); reason != "" {
+				// 未经批准的版本不更新，也不视为紧急更新
+				plus.Urgent = ""
+				plus.Reason = reason
+				plan.Held = append(plan.Held, plus)
+			} else if reason := holdReason(cfg, plus, cfg.SkippedChromePlusVersion, opts); reason != "" {
+				plus.Reason = reason
+				plan.Held = append(plan.Held, plus)
+			} else {
+				plan.Changes = append(plan.Changes, plus)
+			}
+		}
+	}
+
+	appDir := plan.AppDir
+
+	if plan.Change(ComponentChrome) != nil {
+		plan.Downloads = append(plan.Downloads, PlannedDownload{
+			Component: ComponentChrome,
+			URLs:      latest.ChromeURLs,
+			Size:      latest.ChromeSize,
+			SHA256:    latest.ChromeSHA256,
+			File:      fmt.Sprintf("chrome_installer_%s.exe", latest.ChromeVersion),
+		})
+		plan.Replace = append(plan.Replace, replaceFiles(appDir, ComponentChrome, latest.ChromeVersion)...)
+		plan.Cleanup = planCleanup(appDir, state.VersionDirs, latest.ChromeVersion, cfg.GetKeepVersions())
+	}
+
+	if plan.Change(ComponentChromePlus) != nil && len(latest.ChromePlusURLs) > 0 {
+		plan.Downloads = append(plan.Downloads, PlannedDownload{
+			Component: ComponentChromePlus,
+			URLs:      latest.ChromePlusURLs,
+			Size:      latest.ChromePlusSize,
+			SHA256:    latest.ChromePlusSHA256,
+			File:      fmt.Sprintf("chrome_plus_%s%s", latest.ChromePlusVersion, chromePlusExt(latest.ChromePlusAsset)),
+		})
+		plan.Replace = append(plan.Replace, replaceFiles(appDir, ComponentChromePlus, latest.ChromePlusVersion)...)
+	}
+
+	return plan
+}
+
+// holdReason 返回组件有新版本但暂不更新的原因，应当更新时返回空字符串
+// 紧急更新忽略已跳过的版本和暂停提醒，团队策略不允许跳过时忽略已跳过的版本
+func holdReason(cfg *Config, change ComponentChange, skipped string, opts PlanOptions) string {
+	if opts.IgnoreSkipped || change.Urgent != "" {
+		return ""
+	}
+	if skipped == change.To && !change.NoSkip {
+		return "已跳过此版本"
+	}
+	return snoozeReason(cfg, change.Component, opts)
+}
+
+// soakReason 最新 Chrome 版本发布时长不足策略要求时返回原因，否则返回空字符串
+func soakReason(cfg *Config, opts PlanOptions) string {
+	minAge := cfg.Policy.GetMinReleaseAge()
+	if minAge <= 0 || opts.IgnoreSoak || opts.ChromeReleasedAt.IsZero() {
+		return ""
+	}
+	if opts.Now.Sub(opts.ChromeReleasedAt) >= minAge {
+		return ""
+	}
+	return fmt.Sprintf("发布未满 %s，%s 后提供", formatDuration(minAge),
+		opts.ChromeReleasedAt.Add(minAge).Local().Format("2006-01-02 15:04"))
+}
+
+// formatDuration 以天或小时格式化时长
+func formatDuration(d time.Duration) string {
+	hours := int(d.Hours())
+	if hours >= 24 && hours%24 == 0 {
+		return fmt.Sprintf("%d 天", hours/24)
+	}
+	return fmt.Sprintf("%d 小时", hours)
+}
+
+// urgency 根据更新策略判断组件更新是否紧急，返回原因，不紧急时返回空字符串
+func urgency(cfg *Config, component, installed, latest string, now time.Time) string {
+	policy := cfg.Policy
+
+	if component == ComponentChrome && policy.ForceMajorBehind > 0 && installed != "" {
+		if behind := majorVersion(latest) - majorVersion(installed); behind >= policy.ForceMajorBehind {
+			return fmt.Sprintf("已落后 %d 个大版本", behind)
+		}
+	}
+
+	if seenAt := cfg.UpdateSeenAt(component); policy.NoSkipAfterDays > 0 && seenAt != nil {
+		if days := int(now.Sub(*seenAt).Hours() / 24); days >= policy.NoSkipAfterDays {
+			return fmt.Sprintf("新版本已发现 %d 天", days)
+		}
+	}
+	return ""
+}
+
+// majorVersion 获取版本号的主版本号，无法解析时返回 0
+func majorVersion(version string) int {
+	var major int
+	fmt.Sscanf(strings.TrimPrefix(strings.TrimSpace(version), "v"), "%d", &major)
+	return major
+}
+
+// RecordUpdateSeen 记录首次发现各组件有未安装新版本的时间，组件已是最新时清除记录
+// 返回配置是否被修改
+func RecordUpdateSeen(cfg *Config, state InstallState, latest *VersionInfo, now time.Time) bool {
+	changed := false
+	record := func(component string, pending bool) {
+		seenAt := cfg.UpdateSeenAt(component)
+		switch {
+		case pending && seenAt == nil:
+			cfg.SetUpdateSeenAt(component, &now)
+			changed = true
+		case !pending && seenAt != nil:
+			cfg.SetUpdateSeenAt(component, nil)
+			changed = true
+		}
+	}
+
+	record(ComponentChrome, state.ChromeExists && cfg.Version != "" && CompareVersion(cfg.Version, latest.ChromeVersion))
+	record(ComponentChromePlus, state.ChromePlusExists && cfg.ChromePlusVersion != "" &&
+		latest.ChromePlusVersion != "" && !sameTag(cfg.ChromePlusVersion, latest.ChromePlusVersion))
+	return changed
+}
+
+// snoozeReason 组件处于暂停提醒期间时返回原因，否则返回空字符串
+// 截止时间不超过当前配置允许的最长时长
+func snoozeReason(cfg *Config, component string, opts PlanOptions) string {
+	if opts.IgnoreSkipped {
+		return ""
+	}
+	if until := cfg.SnoozeUntil(component); until != nil {
+		limit := opts.Now.Add(cfg.GetMaxSnooze())
+		if until.After(limit) {
+			until = &limit
+		}
+		if opts.Now.Before(*until) {
+			return fmt.Sprintf("稍后提醒，%s 前不再提示", until.Local().Format("2006-01-02 15:04"))
+		}
+	}
+	return ""
+}
+
+// replaceFiles 更新组件时将被替换的文件（相对程序目录）
+func replaceFiles(appDir, component, version string) []string {
+	if component == ComponentChromePlus {
+		return []string{
+			filepath.Join(appDir, "version.dll"),
+			filepath.Join(appDir, "chrome++.ini"),
+		}
+	}
+	return []string{
+		filepath.Join(appDir, "chrome.exe"),
+		filepath.Join(appDir, "chrome_proxy.exe"),
+		filepath.Join(appDir, version),
+	}
+}
+
+// Drop 从计划中移除组件的更新，并以指定原因记入 Held
+func (p *UpdatePlan) Drop(component, reason string) {
+	change := p.Change(component)
+	if change == nil {
+		return
+	}
+	held := *change
+	held.Reason = reason
+	p.Held = append(p.Held, held)
+
+	var changes []ComponentChange
+	for _, c := range p.Changes {
+		if c.Component != component {
+			changes = append(changes, c)
+		}
+	}
+	p.Changes = append([]ComponentChange{}, changes...)
+
+	var downloads []PlannedDownload
+	for _, d := range p.Downloads {
+		if d.Component != component {
+			downloads = append(downloads, d)
+		}
+	}
+	p.Downloads = append([]PlannedDownload{}, downloads...)
+
+	// 移除该组件对应的替换文件
+	files := replaceFiles(p.AppDir, component, held.To)
+	var replace []string
+	for _, f := range p.Replace {
+		if !containsString(files, f) {
+			replace = append(replace, f)
+		}
+	}
+	p.Replace = append([]string{}, replace...)
+
+	// 不更新 Chrome 时无需清理旧版本
+	if component == ComponentChrome {
+		p.Cleanup = []string{}
+	}
+}
+
+// planCleanup 计算安装新版本后需要清理的旧版本目录
+func planCleanup(appDir string, versionDirs []string, newVersion string, keepCount int) []string {
+	dirs := append([]string{}, versionDirs...)
+	if versionDirRegex.MatchString(newVersion) && !containsString(dirs, newVersion) {
+		dirs = append(dirs, newVersion)
+	}
+
+	// 如果版本目录数量不超过保留数量，无需清理
+	if len(dirs) <= keepCount {
+		return []string{}
+	}
+
+	// 按版本号排序（降序，最新的在前）
+	sort.Slice(dirs, func(i, j int) bool {
+		return CompareVersion(dirs[j], dirs[i])
+	})
+
+	var cleanup []string
+	for _, v := range dirs[keepCount:] {
+		cleanup = append(cleanup, filepath.Join(appDir, v))
+	}
+	return cleanup
+}
+
+// containsString 判断切片是否包含指定字符串
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteText 以文本形式输出更新计划
+func (p *UpdatePlan) WriteText(w io.Writer) {
+	fmt.Fprintf(w, "更新通道: %s\n", p.Channel)
+	if p.Empty() {
+		fmt.Fprintln(w, "已是最新版本，无需更新")
+	}
+
+	for _, c := range p.Changes {
+		from := c.From
+		if c.Install || from == "" {
+			from = "未安装"
+		}
+		if c.Urgent != "" {
+			fmt.Fprintf(w, "紧急更新 %s: %s → %s（%s）\n", componentName(c.Component), from, c.To, c.Urgent)
+		} else {
+			fmt.Fprintf(w, "更新 %s: %s → %s\n", componentName(c.Component), from, c.To)
+		}
+	}
+	for _, c := range p.Held {
+		fmt.Fprintf(w, "暂不更新 %s: %s → %s（%s）\n", componentName(c.Component), c.From, c.To, c.Reason)
+	}
+
+	for _, d := range p.Downloads {
+		size := "未知大小"
+		if d.Size > 0 {
+			size = FormatBytes(d.Size)
+		}
+		fmt.Fprintf(w, "下载 %s (%s):\n", componentName(d.Component), size)
+		for _, u := range d.URLs {
+			fmt.Fprintf(w, "  %s\n", u)
+		}
+	}
+	if len(p.Replace) > 0 {
+		fmt.Fprintf(w, "替换:\n  %s\n", strings.Join(p.Replace, "\n  "))
+	}
+	if len(p.Cleanup) > 0 {
+		fmt.Fprintf(w, "清理:\n  %s\n", strings.Join(p.Cleanup, "\n  "))
+	}
+}
+
+// WriteJSON 以 JSON 形式输出更新计划
+func (p *UpdatePlan) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(p)
+}
+
+// componentName 组件显示名称
+func componentName(component string) string {
+	if component == ComponentChromePlus {
+		return "Chrome++"
+	}
+	return "Chrome"
+}
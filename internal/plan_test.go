@@ -0,0 +1,207 @@
+package internal
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildUpdatePlan(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	snoozeUntil := now.Add(2 * time.Hour)
+	installed := InstallState{ChromeExists: true, ChromePlusExists: true, VersionDirs: []string{"120.0.1.0"}}
+
+	tests := []struct {
+		name          string
+		config        func(cfg *Config)
+		state         InstallState
+		latest        VersionInfo
+		opts          PlanOptions
+		wantChanges   []string
+		wantHeld      map[string]string // 组件 -> 原因前缀
+		wantDownloads []string
+		wantCleanup   []string
+	}{
+		{
+			name:          "已是最新",
+			state:         installed,
+			latest:        VersionInfo{ChromeVersion: "120.0.1.0", ChromePlusVersion: "v1.0.0"},
+			wantChanges:   []string{},
+			wantHeld:      map[string]string{},
+			wantDownloads: []string{},
+			wantCleanup:   []string{},
+		},
+		{
+			name:          "跳过的版本",
+			config:        func(cfg *Config) { cfg.SkippedChromeVersion = "121.0.1.0" },
+			state:         installed,
+			latest:        VersionInfo{ChromeVersion: "121.0.1.0", ChromePlusVersion: "v1.0.0"},
+			wantChanges:   []string{},
+			wantHeld:      map[string]string{ComponentChrome: "已跳过此版本"},
+			wantDownloads: []string{},
+			wantCleanup:   []string{},
+		},
+		{
+			name:          "跳过的版本在静默模式下仍更新",
+			config:        func(cfg *Config) { cfg.SkippedChromeVersion = "121.0.1.0" },
+			state:         installed,
+			latest:        VersionInfo{ChromeVersion: "121.0.1.0", ChromePlusVersion: "v1.0.0"},
+			opts:          PlanOptions{IgnoreSkipped: true},
+			wantChanges:   []string{ComponentChrome},
+			wantHeld:      map[string]string{},
+			wantDownloads: []string{"chrome_installer_121.0.1.0.exe"},
+			wantCleanup:   []string{},
+		},
+		{
+			name:          "暂停提醒",
+			config:        func(cfg *Config) { cfg.ChromePlusSnoozeUntil = &snoozeUntil },
+			state:         installed,
+			latest:        VersionInfo{ChromeVersion: "120.0.1.0", ChromePlusVersion: "v1.1.0"},
+			wantChanges:   []string{},
+			wantHeld:      map[string]string{ComponentChromePlus: "稍后提醒"},
+			wantDownloads: []string{},
+			wantCleanup:   []string{},
+		},
+		{
+			name: "紧急更新忽略暂停提醒",
+			config: func(cfg *Config) {
+				cfg.ChromeSnoozeUntil = &snoozeUntil
+				cfg.Policy.ForceMajorBehind = 2
+			},
+			state:         installed,
+			latest:        VersionInfo{ChromeVersion: "122.0.1.0", ChromePlusVersion: "v1.0.0"},
+			wantChanges:   []string{ComponentChrome},
+			wantHeld:      map[string]string{},
+			wantDownloads: []string{"chrome_installer_122.0.1.0.exe"},
+			wantCleanup:   []string{},
+		},
+		{
+			name:  "只更新 Chrome++",
+			state: installed,
+			latest: VersionInfo{
+				ChromeVersion:     "120.0.1.0",
+				ChromePlusVersion: "v1.1.0",
+				ChromePlusURLs:    []string{"https://example.com/Chrome++_v1.1.0.zip"},
+				ChromePlusAsset:   "Chrome++_v1.1.0.zip",
+			},
+			wantChanges:   []string{ComponentChromePlus},
+			wantHeld:      map[string]string{},
+			wantDownloads: []string{"chrome_plus_v1.1.0.zip"},
+			wantCleanup:   []string{},
+		},
+		{
+			name:  "团队固定 Chrome++ 版本时只更新 Chrome",
+			state: installed,
+			latest: VersionInfo{
+				ChromeVersion:     "121.0.1.0",
+				ChromeURLs:        []string{"https://example.com/chrome.exe"},
+				ChromePlusVersion: "v1.1.0",
+				ChromePlusURLs:    []string{"https://example.com/Chrome++_v1.1.0.7z"},
+			},
+			opts:          PlanOptions{Team: &TeamPolicy{ChromePlusTag: "v1.0.0"}},
+			wantChanges:   []string{ComponentChrome},
+			wantHeld:      map[string]string{ComponentChromePlus: "团队批准的版本为 v1.0.0"},
+			wantDownloads: []string{"chrome_installer_121.0.1.0.exe"},
+			wantCleanup:   []string{},
+		},
+		{
+			name:   "清理旧版本",
+			config: func(cfg *Config) { cfg.KeepVersions = 2 },
+			state: InstallState{
+				ChromeExists:     true,
+				ChromePlusExists: true,
+				VersionDirs:      []string{"119.0.1.0", "120.0.1.0", "118.0.1.0"},
+			},
+			latest:        VersionInfo{ChromeVersion: "121.0.1.0", ChromePlusVersion: "v1.0.0"},
+			wantChanges:   []string{ComponentChrome},
+			wantHeld:      map[string]string{},
+			wantDownloads: []string{"chrome_installer_121.0.1.0.exe"},
+			wantCleanup:   []string{filepath.Join("App", "119.0.1.0"), filepath.Join("App", "118.0.1.0")},
+		},
+		{
+			name:          "首次安装",
+			state:         InstallState{},
+			latest:        VersionInfo{ChromeVersion: "121.0.1.0", ChromePlusVersion: "v1.0.0", ChromePlusURLs: []string{"https://example.com/a.7z"}},
+			wantChanges:   []string{ComponentChrome, ComponentChromePlus},
+			wantHeld:      map[string]string{},
+			wantDownloads: []string{"chrome_installer_121.0.1.0.exe", "chrome_plus_v1.0.0.7z"},
+			wantCleanup:   []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Version = "120.0.1.0"
+			cfg.ChromePlusVersion = "v1.0.0"
+			if tt.config != nil {
+				tt.config(cfg)
+			}
+			opts := tt.opts
+			opts.Now = now
+
+			plan := BuildUpdatePlan(cfg, tt.state, &tt.latest, opts)
+
+			changes := []string{}
+			for _, c := range plan.Changes {
+				changes = append(changes, c.Component)
+			}
+			if !reflect.DeepEqual(changes, tt.wantChanges) {
+				t.Errorf("Changes = %v, want %v", changes, tt.wantChanges)
+			}
+
+			if len(plan.Held) != len(tt.wantHeld) {
+				t.Errorf("Held = %+v, want %v", plan.Held, tt.wantHeld)
+			}
+			for _, c := range plan.Held {
+				want, ok := tt.wantHeld[c.Component]
+				if !ok || !strings.HasPrefix(c.Reason, want) {
+					t.Errorf("Held %s reason = %q, want prefix %q", c.Component, c.Reason, want)
+				}
+			}
+
+			downloads := []string{}
+			for _, d := range plan.Downloads {
+				downloads = append(downloads, d.File)
+			}
+			if !reflect.DeepEqual(downloads, tt.wantDownloads) {
+				t.Errorf("Downloads = %v, want %v", downloads, tt.wantDownloads)
+			}
+
+			if !reflect.DeepEqual(plan.Cleanup, tt.wantCleanup) {
+				t.Errorf("Cleanup = %v, want %v", plan.Cleanup, tt.wantCleanup)
+			}
+		})
+	}
+}
+
+func TestUpdatePlanDrop(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Version = "120.0.1.0"
+	cfg.ChromePlusVersion = "v1.0.0"
+	state := InstallState{ChromeExists: true, ChromePlusExists: true, VersionDirs: []string{"120.0.1.0", "119.0.1.0", "118.0.1.0"}}
+	latest := &VersionInfo{ChromeVersion: "121.0.1.0", ChromePlusVersion: "v1.1.0", ChromePlusURLs: []string{"https://example.com/a.7z"}}
+
+	plan := BuildUpdatePlan(cfg, state, latest, PlanOptions{})
+	plan.Drop(ComponentChrome, "稍后提醒")
+
+	if len(plan.Changes) != 1 || plan.Changes[0].Component != ComponentChromePlus {
+		t.Fatalf("Changes = %+v", plan.Changes)
+	}
+	if len(plan.Held) != 1 || plan.Held[0].Reason != "稍后提醒" {
+		t.Fatalf("Held = %+v", plan.Held)
+	}
+	if plan.Download(ComponentChrome) != nil || plan.Download(ComponentChromePlus) == nil {
+		t.Fatalf("Downloads = %+v", plan.Downloads)
+	}
+	if len(plan.Cleanup) != 0 {
+		t.Fatalf("Cleanup = %v", plan.Cleanup)
+	}
+	for _, f := range plan.Replace {
+		if strings.Contains(f, "chrome.exe") {
+			t.Fatalf("Replace 中仍有 Chrome 的文件: %v", plan.Replace)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Google VersionHistory API，提供各版本开始推送的时间
+const versionHistoryURL = "https://versionhistory.googleapis.com"
+
+// ReleaseTimeSource 版本发布时间来源
+type ReleaseTimeSource interface {
+	// ReleaseTime 返回指定通道中该版本开始发布的时间
+	ReleaseTime(channel, version string) (time.Time, error)
+}
+
+// VersionHistorySource 基于 Google VersionHistory API 的发布时间来源
+// BaseURL 可指向本地替身服务，便于测试
+type VersionHistorySource struct {
+	BaseURL  string          // API 地址，默认 https://versionhistory.googleapis.com
+	Platform string          // 平台，默认 win64
+	Client   *MetadataClient // 元数据客户端，用于缓存查询结果
+}
+
+// versionHistoryReleases VersionHistory releases 接口响应结构
+type versionHistoryReleases struct {
+	Releases []struct {
+		Serving struct {
+			StartTime time.Time `json:"startTime"`
+		} `json:"serving"`
+	} `json:"releases"`
+}
+
+// ReleaseTime 查询版本在通道中最早开始推送的时间
+func (v *VersionHistorySource) ReleaseTime(channel, version string) (time.Time, error) {
+	base := v.BaseURL
+	if base == "" {
+		base = versionHistoryURL
+	}
+	platform := v.Platform
+	if platform == "" {
+		platform = "win64"
+	}
+	if channel == "" {
+		channel = "stable"
+	}
+
+	endpoint := fmt.Sprintf("%s/v1/chrome/platforms/%s/channels/%s/versions/%s/releases",
+		strings.TrimRight(base, "/"), url.PathEscape(platform), url.PathEscape(strings.ToLower(channel)), url.PathEscape(version))
+
+	client := v.Client
+	if client == nil {
+		client = &MetadataClient{}
+	}
+
+	var data versionHistoryReleases
+	if err := client.FetchJSON(endpoint, nil, &data); err != nil {
+		return time.Time{}, err
+	}
+
+	var earliest time.Time
+	for _, r := range data.Releases {
+		start := r.Serving.StartTime
+		if !start.IsZero() && (earliest.IsZero() || start.Before(earliest)) {
+			earliest = start
+		}
+	}
+	if earliest.IsZero() {
+		return time.Time{}, fmt.Errorf("未找到版本 %s 的发布时间", version)
+	}
+	return earliest, nil
+}
+
+// RecordVersionSeen 记录首次发现最新 Chrome 版本的时间，返回配置是否被修改
+func RecordVersionSeen(cfg *Config, latest *VersionInfo, now time.Time) bool {
+	if latest.ChromeVersion == "" {
+		return false
+	}
+	if cfg.ChromeVersionSeen != nil && cfg.ChromeVersionSeen.Version == latest.ChromeVersion {
+		return false
+	}
+	cfg.ChromeVersionSeen = &SeenVersion{Version: latest.ChromeVersion, FirstSeen: now}
+	return true
+}
+
+// chromeReleaseTime 获取最新 Chrome 版本的发布时间
+// 优先使用 VersionHistory API，失败时退回本地记录的首次发现时间
+func chromeReleaseTime(cfg *Config, source ReleaseTimeSource, latest *VersionInfo) time.Time {
+	if source != nil {
+		if t, err := source.ReleaseTime(cfg.Channel, latest.ChromeVersion); err == nil {
+			return t
+		}
+	}
+	if seen := cfg.ChromeVersionSeen; seen != nil && seen.Version == latest.ChromeVersion {
+		return seen.FirstSeen
+	}
+	return time.Time{}
+}
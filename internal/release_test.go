@@ -0,0 +1,112 @@
+package internal
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newVersionHistoryServer 返回 VersionHistory API 替身服务，releases 为版本 -> 各次推送的开始时间
+func newVersionHistoryServer(t *testing.T, releases map[string][]time.Time) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		const prefix = "/v1/chrome/platforms/win64/channels/stable/versions/"
+		version, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, prefix), "/releases")
+		if !strings.HasPrefix(r.URL.Path, prefix) || !ok {
+			http.NotFound(w, r)
+			return
+		}
+		var resp versionHistoryReleases
+		for _, start := range releases[version] {
+			var release struct {
+				Serving struct {
+					StartTime time.Time `json:"startTime"`
+				} `json:"serving"`
+			}
+			release.Serving.StartTime = start
+			resp.Releases = append(resp.Releases, release)
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestVersionHistorySourceReleaseTime(t *testing.T) {
+	first := time.Date(2024, 4, 30, 8, 0, 0, 0, time.UTC)
+	server := newVersionHistoryServer(t, map[string][]time.Time{
+		"121.0.1.0": {first.Add(24 * time.Hour), first},
+	})
+	source := &VersionHistorySource{BaseURL: server.URL}
+
+	got, err := source.ReleaseTime("Stable", "121.0.1.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(first) {
+		t.Fatalf("ReleaseTime = %v, want 最早的推送时间 %v", got, first)
+	}
+
+	if _, err := source.ReleaseTime("stable", "122.0.1.0"); err == nil {
+		t.Fatal("没有发布记录时应返回错误")
+	}
+}
+
+func TestChromeReleaseSoak(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	server := newVersionHistoryServer(t, map[string][]time.Time{
+		"121.0.1.0": {now.Add(-time.Hour)},
+		"122.0.1.0": {now.Add(-4 * 24 * time.Hour)},
+	})
+
+	tests := []struct {
+		name     string
+		version  string
+		seen     *SeenVersion
+		wantHeld bool
+	}{
+		{name: "发布时长不足", version: "121.0.1.0", wantHeld: true},
+		{name: "发布时长已满", version: "122.0.1.0"},
+		{
+			name:     "缺少发布时间时使用首次发现时间",
+			version:  "123.0.1.0",
+			seen:     &SeenVersion{Version: "123.0.1.0", FirstSeen: now.Add(-time.Hour)},
+			wantHeld: true,
+		},
+		{
+			name:    "首次发现时间已满",
+			version: "123.0.1.0",
+			seen:    &SeenVersion{Version: "123.0.1.0", FirstSeen: now.Add(-4 * 24 * time.Hour)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Version = "120.0.1.0"
+			cfg.ChromePlusVersion = "v1.0.0"
+			cfg.Policy.MinReleaseAge = "3d"
+			cfg.ChromeVersionSeen = tt.seen
+			latest := &VersionInfo{ChromeVersion: tt.version, ChromePlusVersion: "v1.0.0"}
+
+			source := &VersionHistorySource{BaseURL: server.URL}
+			released := chromeReleaseTime(cfg, source, latest)
+			if released.IsZero() {
+				t.Fatal("未获取到发布时间")
+			}
+
+			state := InstallState{ChromeExists: true, ChromePlusExists: true, VersionDirs: []string{"120.0.1.0"}}
+			plan := BuildUpdatePlan(cfg, state, latest, PlanOptions{Now: now, ChromeReleasedAt: released})
+			held := len(plan.Held) == 1 && strings.HasPrefix(plan.Held[0].Reason, "发布未满 3 天")
+			if held != tt.wantHeld {
+				t.Fatalf("Held = %+v, Changes = %+v, want held %v", plan.Held, plan.Changes, tt.wantHeld)
+			}
+			if !tt.wantHeld && plan.Change(ComponentChrome) == nil {
+				t.Fatalf("发布时长已满时应更新: %+v", plan.Changes)
+			}
+		})
+	}
+}
@@ -0,0 +1,213 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	reportAttempts = 3  // 单次上报的尝试次数
+	reportQueueMax = 50 // 离线队列最多保存的报告数，超出时丢弃最早的报告
+)
+
+// reportRetryDelay 上报失败后的重试间隔，依次翻倍
+var reportRetryDelay = time.Second
+
+// StatusReport 上报到资产清单服务的状态
+type StatusReport struct {
+	Hostname          string            `json:"hostname"`
+	ChromeGoVersion   string            `json:"chromego_version"`
+	ChromeGoCommit    string            `json:"chromego_commit"`
+	ChromeVersion     string            `json:"chrome_version"`
+	ChromePlusVersion string            `json:"chrome_plus_version"`
+	Channel           string            `json:"channel"`
+	Command           string            `json:"command,omitempty"` // 子命令，为空表示启动时检查
+	Result            string            `json:"result"`            // 检查结果: up_to_date/updated/failed/browser_running/deferred
+	Error             string            `json:"error,omitempty"`   // 失败原因
+	Pending           []ComponentChange `json:"pending"`           // 待更新（含暂不更新）的版本
+	SkippedChrome     string            `json:"skipped_chrome,omitempty"`
+	SkippedChromePlus string            `json:"skipped_chrome_plus,omitempty"`
+	Time              time.Time         `json:"time"`
+}
+
+// exitResult 退出码对应的检查结果
+func exitResult(code int) string {
+	switch code {
+	case ExitUpToDate:
+		return "up_to_date"
+	case ExitUpdated:
+		return "updated"
+	case ExitBrowserRunning:
+		return "browser_running"
+	case ExitDeferred:
+		return "deferred"
+	default:
+		return "failed"
+	}
+}
+
+// buildStatusReport 根据配置、更新计划和退出码生成状态报告，plan 为 nil 表示未计算更新计划
+func buildStatusReport(cfg *Config, command string, plan *UpdatePlan, code int, errMessage string, now time.Time) *StatusReport {
+	hostname, _ := os.Hostname()
+	report := &StatusReport{
+		Hostname:          hostname,
+		ChromeGoVersion:   Version,
+		ChromeGoCommit:    Commit,
+		ChromeVersion:     cfg.Version,
+		ChromePlusVersion: cfg.ChromePlusVersion,
+		Channel:           cfg.Channel,
+		Command:           command,
+		Result:            exitResult(code),
+		Error:             errMessage,
+		Pending:           []ComponentChange{},
+		SkippedChrome:     cfg.SkippedChromeVersion,
+		SkippedChromePlus: cfg.SkippedChromePlusVersion,
+		Time:              now,
+	}
+	// 已完成的更新不再列为待更新
+	if plan != nil && code != ExitUpdated {
+		report.Pending = append(report.Pending, plan.Changes...)
+	}
+	if plan != nil {
+		report.Pending = append(report.Pending, plan.Held...)
+	}
+	return report
+}
+
+// reportQueuePath 返回离线上报队列文件路径
+func reportQueuePath() string {
+	return filepath.Join(programDir(), "report_queue.json")
+}
+
+// loadReportQueue 读取离线上报队列，文件不存在或损坏时返回空队列
+func loadReportQueue(path string) []*StatusReport {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var queue []*StatusReport
+	if json.Unmarshal(data, &queue) != nil {
+		return nil
+	}
+	return queue
+}
+
+// saveReportQueue 保存离线上报队列，队列为空时删除文件
+func saveReportQueue(path string, queue []*StatusReport) error {
+	if len(queue) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if len(queue) > reportQueueMax {
+		queue = queue[len(queue)-reportQueueMax:]
+	}
+	data, err := json.MarshalIndent(queue, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// reportError 上报失败，Retry 表示稍后重试可能成功（网络错误、服务器错误、限流）
+type reportError struct {
+	err   error
+	Retry bool
+}
+
+func (e *reportError) Error() string {
+	return e.err.Error()
+}
+
+// postReport 上报单个状态，可重试的错误按间隔重试
+func postReport(endpoint string, report *StatusReport) *reportError {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return &reportError{err: err}
+	}
+	client := &http.Client{Transport: httpClient.Transport, Timeout: 15 * time.Second}
+
+	delay := reportRetryDelay
+	for attempt := 1; ; attempt++ {
+		err := sendReport(client, endpoint, body)
+		if err == nil {
+			return nil
+		}
+		if !err.Retry || attempt >= reportAttempts {
+			return err
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// sendReport 发送一次上报请求
+func sendReport(client *http.Client, endpoint string, body []byte) *reportError {
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return &reportError{err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ChromeGo/"+Version)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return &reportError{err: err, Retry: true}
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return &reportError{err: &HTTPError{StatusCode: resp.StatusCode}, Retry: true}
+	default:
+		return &reportError{err: &HTTPError{StatusCode: resp.StatusCode}}
+	}
+}
+
+// SendReport 上报状态：先按顺序补发离线队列中的报告，再上报本次状态
+// 可重试的失败将报告留在队列中下次补发，服务器拒绝的报告直接丢弃
+func SendReport(endpoint, queuePath string, report *StatusReport) error {
+	queue := append(loadReportQueue(queuePath), report)
+
+	var firstErr error
+	for i, r := range queue {
+		err := postReport(endpoint, r)
+		if err == nil {
+			continue
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+		if err.Retry {
+			// 服务不可用，剩余的报告留待下次
+			remaining := queue[i:]
+			if saveErr := saveReportQueue(queuePath, remaining); saveErr != nil {
+				return saveErr
+			}
+			return fmt.Errorf("%w（%d 个报告已加入离线队列）", err, len(remaining))
+		}
+	}
+	if err := saveReportQueue(queuePath, nil); err != nil {
+		return err
+	}
+	return firstErr
+}
+
+// report 上报本次检查或更新的结果，失败时只记录警告
+func (s *session) report(code int) {
+	if s.cfg.ReportURL == "" {
+		return
+	}
+	status := buildStatusReport(s.cfg, s.opts.Command, s.plan, code, s.lastError, time.Now())
+	if err := SendReport(s.cfg.ReportURL, reportQueuePath(), status); err != nil {
+		s.warn("上报状态失败: " + err.Error())
+	}
+}
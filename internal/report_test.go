@@ -0,0 +1,148 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// reportServer 记录收到的报告，按 status 依次返回状态码，用尽后返回 200
+type reportServer struct {
+	mu       sync.Mutex
+	status   []int
+	requests int
+	received []*StatusReport
+}
+
+func newReportServer(t *testing.T, status ...int) (*reportServer, string) {
+	t.Helper()
+	rs := &reportServer{status: status}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rs.mu.Lock()
+		defer rs.mu.Unlock()
+		rs.requests++
+		if len(rs.status) > 0 {
+			code := rs.status[0]
+			rs.status = rs.status[1:]
+			if code != http.StatusOK {
+				w.WriteHeader(code)
+				return
+			}
+		}
+		var report StatusReport
+		if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		rs.received = append(rs.received, &report)
+	}))
+	t.Cleanup(server.Close)
+	return rs, server.URL
+}
+
+// setupReportTest 缩短重试间隔，返回临时的离线队列路径
+func setupReportTest(t *testing.T) string {
+	t.Helper()
+	oldDelay := reportRetryDelay
+	reportRetryDelay = time.Millisecond
+	t.Cleanup(func() { reportRetryDelay = oldDelay })
+	return filepath.Join(t.TempDir(), "report_queue.json")
+}
+
+func testReport(version string) *StatusReport {
+	return &StatusReport{ChromeVersion: version, Result: "up_to_date", Pending: []ComponentChange{}}
+}
+
+func TestSendReportRetriesServerError(t *testing.T) {
+	queuePath := setupReportTest(t)
+	rs, endpoint := newReportServer(t, http.StatusServiceUnavailable, http.StatusBadGateway)
+
+	if err := SendReport(endpoint, queuePath, testReport("121.0.1.0")); err != nil {
+		t.Fatal(err)
+	}
+	if rs.requests != 3 || len(rs.received) != 1 {
+		t.Fatalf("requests = %d, received = %d, want 3 次请求后成功", rs.requests, len(rs.received))
+	}
+	if _, err := os.Stat(queuePath); !os.IsNotExist(err) {
+		t.Fatalf("成功后不应留下离线队列: %v", err)
+	}
+}
+
+func TestSendReportQueuesWhenOffline(t *testing.T) {
+	queuePath := setupReportTest(t)
+	server := httptest.NewServer(http.NotFoundHandler())
+	endpoint := server.URL
+	server.Close()
+
+	if err := SendReport(endpoint, queuePath, testReport("120.0.1.0")); err == nil {
+		t.Fatal("服务不可用时应返回错误")
+	}
+	if err := SendReport(endpoint, queuePath, testReport("121.0.1.0")); err == nil {
+		t.Fatal("服务不可用时应返回错误")
+	}
+	queue := loadReportQueue(queuePath)
+	if len(queue) != 2 || queue[0].ChromeVersion != "120.0.1.0" || queue[1].ChromeVersion != "121.0.1.0" {
+		t.Fatalf("离线队列 = %+v", queue)
+	}
+
+	// 服务恢复后按顺序补发
+	rs, endpoint := newReportServer(t)
+	if err := SendReport(endpoint, queuePath, testReport("122.0.1.0")); err != nil {
+		t.Fatal(err)
+	}
+	var versions []string
+	for _, r := range rs.received {
+		versions = append(versions, r.ChromeVersion)
+	}
+	if fmt.Sprint(versions) != "[120.0.1.0 121.0.1.0 122.0.1.0]" {
+		t.Fatalf("补发顺序 = %v", versions)
+	}
+	if _, err := os.Stat(queuePath); !os.IsNotExist(err) {
+		t.Fatalf("补发后应删除离线队列: %v", err)
+	}
+}
+
+func TestSendReportDropsRejected(t *testing.T) {
+	queuePath := setupReportTest(t)
+	rs, endpoint := newReportServer(t, http.StatusBadRequest)
+
+	if err := SendReport(endpoint, queuePath, testReport("121.0.1.0")); err == nil {
+		t.Fatal("服务器拒绝时应返回错误")
+	}
+	if rs.requests != 1 {
+		t.Fatalf("服务器拒绝的报告不应重试, requests = %d", rs.requests)
+	}
+	if queue := loadReportQueue(queuePath); len(queue) != 0 {
+		t.Fatalf("服务器拒绝的报告不应加入队列: %+v", queue)
+	}
+}
+
+func TestReportQueueLimit(t *testing.T) {
+	queuePath := setupReportTest(t)
+	var queue []*StatusReport
+	for i := 0; i < reportQueueMax; i++ {
+		queue = append(queue, testReport(fmt.Sprintf("100.0.%d.0", i)))
+	}
+	if err := saveReportQueue(queuePath, queue); err != nil {
+		t.Fatal(err)
+	}
+
+	_, endpoint := newReportServer(t, http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusServiceUnavailable)
+	if err := SendReport(endpoint, queuePath, testReport("121.0.1.0")); err == nil {
+		t.Fatal("服务不可用时应返回错误")
+	}
+
+	queue = loadReportQueue(queuePath)
+	if len(queue) != reportQueueMax {
+		t.Fatalf("离线队列长度 = %d, want %d", len(queue), reportQueueMax)
+	}
+	if queue[0].ChromeVersion != "100.0.1.0" || queue[len(queue)-1].ChromeVersion != "121.0.1.0" {
+		t.Fatalf("应丢弃最早的报告, 队列首尾 = %s, %s", queue[0].ChromeVersion, queue[len(queue)-1].ChromeVersion)
+	}
+}
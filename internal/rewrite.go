@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// URL 改写模式
+const (
+	RewritePrepend = "prepend" // 改写后的地址作为最高优先级，保留原地址（默认）
+	RewriteReplace = "replace" // 改写后的地址替换原地址
+)
+
+// URLRewrite 下载地址改写规则，用于将 Chrome 下载引导到内网镜像
+// Match 与 Host 二选一：Match 为正则表达式，Template 中可用 $1、${name} 引用分组；Host 为主机名，匹配时替换为 To
+type URLRewrite struct {
+	Match    string `json:"match"`    // 匹配地址的正则表达式
+	Template string `json:"template"` // 改写后的地址模板
+	Host     string `json:"host"`     // 需要替换的主机名，如 "dl.google.com"
+	To       string `json:"to"`       // 替换后的主机（可带协议），如 "mirror.lan" 或 "http://mirror.lan:8080"
+	Mode     string `json:"mode"`     // prepend 或 replace，默认 prepend
+}
+
+// Rewrite 改写地址，不匹配或规则无效时返回 false
+func (r URLRewrite) Rewrite(rawURL string) (string, bool) {
+	if r.Match != "" {
+		re, err := regexp.Compile(r.Match)
+		if err != nil {
+			return "", false
+		}
+		match := re.FindStringSubmatchIndex(rawURL)
+		if match == nil {
+			return "", false
+		}
+		return string(re.ExpandString(nil, r.Template, rawURL, match)), true
+	}
+
+	if r.Host == "" || r.To == "" {
+		return "", false
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || !strings.EqualFold(u.Hostname(), r.Host) {
+		return "", false
+	}
+	if strings.Contains(r.To, "://") {
+		to, err := url.Parse(r.To)
+		if err != nil {
+			return "", false
+		}
+		u.Scheme = to.Scheme
+		u.Host = to.Host
+		if prefix := strings.TrimRight(to.Path, "/"); prefix != "" {
+			u.Path = prefix + u.Path
+			u.RawPath = ""
+		}
+	} else {
+		u.Host = r.To
+	}
+	return u.String(), true
+}
+
+// replaces 判断改写后是否丢弃原地址
+func (r URLRewrite) replaces() bool {
+	return strings.EqualFold(r.Mode, RewriteReplace)
+}
+
+// rewriteURLs 按规则改写下载地址
+// 返回改写后的地址（按原顺序，作为最高优先级，允许 http）和需要保留的原地址
+// 每个地址只应用第一条匹配的规则
+func rewriteURLs(rules []URLRewrite, urls []string) (rewritten, originals []string) {
+	for _, u := range urls {
+		kept := true
+		for _, rule := range rules {
+			target, ok := rule.Rewrite(u)
+			if !ok {
+				continue
+			}
+			if !containsString(rewritten, target) {
+				rewritten = append(rewritten, target)
+			}
+			kept = !rule.replaces()
+			break
+		}
+		if kept {
+			originals = append(originals, u)
+		}
+	}
+	return rewritten, originals
+}
@@ -0,0 +1,138 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestURLRewrite(t *testing.T) {
+	const installer = "https://dl.google.com/release2/chrome/abc/121.0.1.0_chrome_installer.exe"
+
+	tests := []struct {
+		name   string
+		rule   URLRewrite
+		rawURL string
+		want   string // 为空表示不改写
+	}{
+		{
+			name:   "替换主机",
+			rule:   URLRewrite{Host: "dl.google.com", To: "mirror.lan"},
+			rawURL: installer,
+			want:   "https://mirror.lan/release2/chrome/abc/121.0.1.0_chrome_installer.exe",
+		},
+		{
+			name:   "替换为带协议、端口和路径前缀的主机",
+			rule:   URLRewrite{Host: "DL.google.com", To: "http://mirror.lan:8080/google/"},
+			rawURL: installer,
+			want:   "http://mirror.lan:8080/google/release2/chrome/abc/121.0.1.0_chrome_installer.exe",
+		},
+		{
+			name:   "主机不匹配",
+			rule:   URLRewrite{Host: "www.google.com", To: "mirror.lan"},
+			rawURL: installer,
+		},
+		{
+			name:   "缺少目标主机",
+			rule:   URLRewrite{Host: "dl.google.com"},
+			rawURL: installer,
+		},
+		{
+			name:   "正则分组编号",
+			rule:   URLRewrite{Match: `^https://dl\.google\.com/(.*)/([^/]+)$`, Template: "http://mirror.lan/$2?from=$1"},
+			rawURL: installer,
+			want:   "http://mirror.lan/121.0.1.0_chrome_installer.exe?from=release2/chrome/abc",
+		},
+		{
+			name:   "正则命名分组",
+			rule:   URLRewrite{Match: `(?P<version>\d+(\.\d+){3})_chrome_installer\.exe$`, Template: "https://mirror.lan/chrome/${version}/installer.exe"},
+			rawURL: installer,
+			want:   "https://mirror.lan/chrome/121.0.1.0/installer.exe",
+		},
+		{
+			name:   "正则不匹配",
+			rule:   URLRewrite{Match: `^https://www\.google\.com/`, Template: "https://mirror.lan/$0"},
+			rawURL: installer,
+		},
+		{
+			name:   "无效的正则",
+			rule:   URLRewrite{Match: `^https://dl\.google\.com/(`, Template: "https://mirror.lan/$1"},
+			rawURL: installer,
+		},
+		{
+			name:   "同时配置时使用正则",
+			rule:   URLRewrite{Match: `^https://www\.google\.com/`, Template: "https://a.lan/", Host: "dl.google.com", To: "b.lan"},
+			rawURL: installer,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tt.rule.Rewrite(tt.rawURL)
+			if ok != (tt.want != "") || got != tt.want {
+				t.Fatalf("Rewrite = %q, %v, want %q", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewriteURLs(t *testing.T) {
+	urls := []string{
+		"https://dl.google.com/chrome/installer.exe",
+		"https://www.google.com/chrome/installer.exe",
+		"https://edgedl.me.gvt1.com/chrome/installer.exe",
+	}
+
+	tests := []struct {
+		name          string
+		rules         []URLRewrite
+		wantRewritten []string
+		wantOriginals []string
+	}{
+		{
+			name:          "没有规则",
+			wantOriginals: urls,
+		},
+		{
+			name:          "prepend 保留原地址",
+			rules:         []URLRewrite{{Host: "dl.google.com", To: "mirror.lan"}},
+			wantRewritten: []string{"https://mirror.lan/chrome/installer.exe"},
+			wantOriginals: urls,
+		},
+		{
+			name:          "replace 丢弃原地址",
+			rules:         []URLRewrite{{Match: `^https://[^/]*google\.com/(.*)$`, Template: "http://mirror.lan/$1", Mode: "Replace"}},
+			wantRewritten: []string{"http://mirror.lan/chrome/installer.exe"},
+			wantOriginals: urls[2:],
+		},
+		{
+			name: "只应用第一条匹配的规则",
+			rules: []URLRewrite{
+				{Host: "dl.google.com", To: "a.lan", Mode: RewriteReplace},
+				{Match: `google\.com`, Template: "https://b.lan/chrome/installer.exe"},
+			},
+			wantRewritten: []string{"https://a.lan/chrome/installer.exe", "https://b.lan/chrome/installer.exe"},
+			wantOriginals: urls[1:],
+		},
+		{
+			name: "无效的规则被跳过",
+			rules: []URLRewrite{
+				{Match: `(`, Template: "https://broken.lan/", Mode: RewriteReplace},
+				{Host: "edgedl.me.gvt1.com", To: "mirror.lan", Mode: RewriteReplace},
+			},
+			wantRewritten: []string{"https://mirror.lan/chrome/installer.exe"},
+			wantOriginals: urls[:2],
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rewritten, originals := rewriteURLs(tt.rules, urls)
+			if strings.Join(rewritten, "\n") != strings.Join(tt.wantRewritten, "\n") {
+				t.Fatalf("rewritten = %q, want %q", rewritten, tt.wantRewritten)
+			}
+			if strings.Join(originals, "\n") != strings.Join(tt.wantOriginals, "\n") {
+				t.Fatalf("originals = %q, want %q", originals, tt.wantOriginals)
+			}
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// RollbackCheck 元数据防回滚检查结果
+type RollbackCheck struct {
+	Rollbacks []string // 数据源版本或签名时间低于曾见过的记录（可能是冻结或回滚攻击）
+	Stale     string   // 元数据早于最长有效时长
+}
+
+// Refuse 按配置判断是否拒绝本次更新
+func (r RollbackCheck) Refuse(cfg *Config) bool {
+	return len(r.Rollbacks) > 0 && cfg.GetRollbackAction() == RollbackRefuse
+}
+
+// metadataTime 返回元数据的时间，签名为 true 表示来自受信任的签名
+// 要求签名时使用签名时间，否则使用响应的 Last-Modified（本地文件为修改时间），未知时返回零值
+func (m *MetadataClient) metadataTime(rawURL string, signed bool) (time.Time, bool) {
+	if m.Cache == nil {
+		return time.Time{}, false
+	}
+	if body := m.Cache.get(rawURL); signed && body != nil {
+		if entry := m.Cache.get(bodySignatureURL(rawURL, []byte(body.Body))); entry != nil {
+			var sig Signature
+			if err := json.Unmarshal([]byte(entry.Body), &sig); err == nil && !sig.SignedAt.IsZero() {
+				return sig.SignedAt, true
+			}
+		}
+	}
+	if entry := m.Cache.get(rawURL); entry != nil && entry.LastModified != "" {
+		if t, err := http.ParseTime(entry.LastModified); err == nil {
+			return t, false
+		}
+	}
+	return time.Time{}, false
+}
+
+// CheckRollback 检查最新版本信息是否低于曾见过的记录，以及元数据是否过旧
+// 版本按通道分别记录，指定版本的 Chrome++ 不检查；签名时间只在两次元数据都有签名时比较，未签名的时间可被任意伪造
+func CheckRollback(cfg *Config, latest *VersionInfo, now time.Time) RollbackCheck {
+	var check RollbackCheck
+
+	key := getChromeDataKey(cfg.Channel)
+	if highest := cfg.HighestSeen[key]; highest != "" && latest.ChromeVersion != "" && CompareVersion(latest.ChromeVersion, highest) {
+		check.Rollbacks = append(check.Rollbacks,
+			fmt.Sprintf("数据源中的 Chrome 版本 %s 低于曾见过的 %s（%s）", latest.ChromeVersion, highest, key))
+	}
+	if highest := cfg.HighestSeen[ComponentChromePlus]; highest != "" && latest.ChromePlusVersion != "" && !latest.ChromePlusPinned &&
+		CompareVersion(latest.ChromePlusVersion, highest) {
+		check.Rollbacks = append(check.Rollbacks,
+			fmt.Sprintf("数据源中的 Chrome++ 版本 %s 低于曾见过的 %s", latest.ChromePlusVersion, highest))
+	}
+	if latest.MetadataSigned && cfg.MetadataSignedAt != nil && latest.MetadataTime.Before(*cfg.MetadataSignedAt) {
+		check.Rollbacks = append(check.Rollbacks,
+			fmt.Sprintf("元数据签名时间 %s 早于曾见过的 %s", formatTime(latest.MetadataTime), formatTime(*cfg.MetadataSignedAt)))
+	}
+
+	if maxAge := cfg.GetMaxMetadataAge(); maxAge > 0 && !latest.MetadataTime.IsZero() && now.Sub(latest.MetadataTime) > maxAge {
+		check.Stale = fmt.Sprintf("元数据已过旧: 生成于 %s，超过 %s", formatTime(latest.MetadataTime), formatDuration(maxAge))
+	}
+	return check
+}
+
+// RecordHighestSeen 记录各通道见过的最高版本和最新的元数据签名时间，返回配置是否被修改
+func RecordHighestSeen(cfg *Config, latest *VersionInfo) bool {
+	changed := false
+	record := func(key, version string) {
+		if version == "" {
+			return
+		}
+		if highest := cfg.HighestSeen[key]; highest == "" || CompareVersion(highest, version) {
+			if cfg.HighestSeen == nil {
+				cfg.HighestSeen = make(map[string]string)
+			}
+			cfg.HighestSeen[key] = version
+			changed = true
+		}
+	}
+	record(getChromeDataKey(cfg.Channel), latest.ChromeVersion)
+	if !latest.ChromePlusPinned {
+		record(ComponentChromePlus, latest.ChromePlusVersion)
+	}
+
+	if latest.MetadataSigned && (cfg.MetadataSignedAt == nil || latest.MetadataTime.After(*cfg.MetadataSignedAt)) {
+		at := latest.MetadataTime
+		cfg.MetadataSignedAt = &at
+		changed = true
+	}
+	return changed
+}
+
+// formatTime 格式化为本地时间
+func formatTime(t time.Time) string {
+	return t.Local().Format("2006-01-02 15:04")
+}
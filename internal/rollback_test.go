@@ -0,0 +1,256 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCheckRollback(t *testing.T) {
+	now := time.Date(2026, 10, 18, 8, 0, 0, 0, time.UTC)
+	signedAt := now.Add(-24 * time.Hour)
+	stableKey := getChromeDataKey("stable")
+
+	tests := []struct {
+		name       string
+		configure  func(cfg *Config)
+		latest     VersionInfo
+		wantRefuse bool
+		wantErrors []string // Rollbacks 中应包含的内容，依次对应
+		wantStale  bool
+	}{
+		{
+			name: "版本不低于曾见过的记录",
+			configure: func(cfg *Config) {
+				cfg.HighestSeen = map[string]string{stableKey: "121.0.1.0", ComponentChromePlus: "v1.1.0"}
+			},
+			latest: VersionInfo{ChromeVersion: "121.0.1.0", ChromePlusVersion: "v1.2.0"},
+		},
+		{
+			name:       "Chrome 版本回退时警告",
+			configure:  func(cfg *Config) { cfg.HighestSeen = map[string]string{stableKey: "122.0.1.0"} },
+			latest:     VersionInfo{ChromeVersion: "121.0.1.0"},
+			wantErrors: []string{"Chrome 版本 121.0.1.0 低于曾见过的 122.0.1.0"},
+		},
+		{
+			name: "Chrome 版本回退时按配置拒绝",
+			configure: func(cfg *Config) {
+				cfg.HighestSeen = map[string]string{stableKey: "122.0.1.0"}
+				cfg.RollbackAction = RollbackRefuse
+			},
+			latest:     VersionInfo{ChromeVersion: "121.0.1.0"},
+			wantRefuse: true,
+			wantErrors: []string{"Chrome 版本 121.0.1.0 低于曾见过的 122.0.1.0"},
+		},
+		{
+			name: "其他通道的记录不影响",
+			configure: func(cfg *Config) {
+				cfg.HighestSeen = map[string]string{getChromeDataKey("canary"): "130.0.1.0"}
+				cfg.RollbackAction = RollbackRefuse
+			},
+			latest: VersionInfo{ChromeVersion: "121.0.1.0"},
+		},
+		{
+			name: "Chrome++ 版本回退",
+			configure: func(cfg *Config) {
+				cfg.HighestSeen = map[string]string{ComponentChromePlus: "v1.2.0"}
+				cfg.RollbackAction = RollbackRefuse
+			},
+			latest:     VersionInfo{ChromeVersion: "121.0.1.0", ChromePlusVersion: "v1.1.0"},
+			wantRefuse: true,
+			wantErrors: []string{"Chrome++ 版本 v1.1.0 低于曾见过的 v1.2.0"},
+		},
+		{
+			name: "指定版本的 Chrome++ 不检查",
+			configure: func(cfg *Config) {
+				cfg.HighestSeen = map[string]string{ComponentChromePlus: "v1.2.0"}
+				cfg.RollbackAction = RollbackRefuse
+			},
+			latest: VersionInfo{ChromeVersion: "121.0.1.0", ChromePlusVersion: "v1.1.0", ChromePlusPinned: true},
+		},
+		{
+			name: "签名时间回退",
+			configure: func(cfg *Config) {
+				at := signedAt
+				cfg.MetadataSignedAt = &at
+				cfg.RollbackAction = RollbackRefuse
+			},
+			latest:     VersionInfo{ChromeVersion: "121.0.1.0", MetadataTime: signedAt.Add(-time.Hour), MetadataSigned: true},
+			wantRefuse: true,
+			wantErrors: []string{"元数据签名时间"},
+		},
+		{
+			name: "未签名的时间不比较",
+			configure: func(cfg *Config) {
+				at := signedAt
+				cfg.MetadataSignedAt = &at
+				cfg.RollbackAction = RollbackRefuse
+			},
+			latest: VersionInfo{ChromeVersion: "121.0.1.0", MetadataTime: signedAt.Add(-time.Hour)},
+		},
+		{
+			name:      "元数据过旧时只警告",
+			configure: func(cfg *Config) { cfg.MaxMetadataAge = "14d"; cfg.RollbackAction = RollbackRefuse },
+			latest:    VersionInfo{ChromeVersion: "121.0.1.0", MetadataTime: now.Add(-15 * 24 * time.Hour)},
+			wantStale: true,
+		},
+		{
+			name:      "元数据未过旧",
+			configure: func(cfg *Config) { cfg.MaxMetadataAge = "14d" },
+			latest:    VersionInfo{ChromeVersion: "121.0.1.0", MetadataTime: now.Add(-13 * 24 * time.Hour)},
+		},
+		{
+			name:      "元数据时间未知时不检查",
+			configure: func(cfg *Config) { cfg.MaxMetadataAge = "14d" },
+			latest:    VersionInfo{ChromeVersion: "121.0.1.0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			tt.configure(cfg)
+			check := CheckRollback(cfg, &tt.latest, now)
+
+			if len(check.Rollbacks) != len(tt.wantErrors) {
+				t.Fatalf("Rollbacks = %q, want %q", check.Rollbacks, tt.wantErrors)
+			}
+			for i, want := range tt.wantErrors {
+				if !strings.Contains(check.Rollbacks[i], want) {
+					t.Fatalf("Rollbacks[%d] = %q, want %q", i, check.Rollbacks[i], want)
+				}
+			}
+			if got := check.Refuse(cfg); got != tt.wantRefuse {
+				t.Fatalf("Refuse = %v, want %v", got, tt.wantRefuse)
+			}
+			if got := check.Stale != ""; got != tt.wantStale {
+				t.Fatalf("Stale = %q, want %v", check.Stale, tt.wantStale)
+			}
+		})
+	}
+}
+
+func TestRecordHighestSeen(t *testing.T) {
+	signedAt := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+	later := signedAt.Add(time.Hour)
+	stableKey := getChromeDataKey("stable")
+
+	tests := []struct {
+		name         string
+		highest      map[string]string
+		signedAt     *time.Time
+		latest       VersionInfo
+		wantChanged  bool
+		wantHighest  map[string]string
+		wantSignedAt *time.Time
+	}{
+		{
+			name:        "首次记录",
+			latest:      VersionInfo{ChromeVersion: "121.0.1.0", ChromePlusVersion: "v1.1.0"},
+			wantChanged: true,
+			wantHighest: map[string]string{stableKey: "121.0.1.0", ComponentChromePlus: "v1.1.0"},
+		},
+		{
+			name:        "更高的版本",
+			highest:     map[string]string{stableKey: "121.0.1.0", ComponentChromePlus: "v1.1.0"},
+			latest:      VersionInfo{ChromeVersion: "122.0.1.0", ChromePlusVersion: "v1.1.0"},
+			wantChanged: true,
+			wantHighest: map[string]string{stableKey: "122.0.1.0", ComponentChromePlus: "v1.1.0"},
+		},
+		{
+			name:        "较低的版本不覆盖记录",
+			highest:     map[string]string{stableKey: "122.0.1.0", ComponentChromePlus: "v1.2.0"},
+			latest:      VersionInfo{ChromeVersion: "121.0.1.0", ChromePlusVersion: "v1.1.0"},
+			wantHighest: map[string]string{stableKey: "122.0.1.0", ComponentChromePlus: "v1.2.0"},
+		},
+		{
+			name:        "指定版本的 Chrome++ 不记录",
+			latest:      VersionInfo{ChromeVersion: "121.0.1.0", ChromePlusVersion: "v1.3.0", ChromePlusPinned: true},
+			wantChanged: true,
+			wantHighest: map[string]string{stableKey: "121.0.1.0"},
+		},
+		{
+			name:         "记录更新的签名时间",
+			highest:      map[string]string{stableKey: "121.0.1.0"},
+			signedAt:     &signedAt,
+			latest:       VersionInfo{ChromeVersion: "121.0.1.0", MetadataTime: signedAt.Add(time.Hour), MetadataSigned: true},
+			wantChanged:  true,
+			wantHighest:  map[string]string{stableKey: "121.0.1.0"},
+			wantSignedAt: &later,
+		},
+		{
+			name:         "较早的签名时间不覆盖记录",
+			highest:      map[string]string{stableKey: "121.0.1.0"},
+			signedAt:     &signedAt,
+			latest:       VersionInfo{ChromeVersion: "121.0.1.0", MetadataTime: signedAt.Add(-time.Hour), MetadataSigned: true},
+			wantHighest:  map[string]string{stableKey: "121.0.1.0"},
+			wantSignedAt: &signedAt,
+		},
+		{
+			name:        "未签名的时间不记录",
+			highest:     map[string]string{stableKey: "121.0.1.0"},
+			latest:      VersionInfo{ChromeVersion: "121.0.1.0", MetadataTime: signedAt},
+			wantHighest: map[string]string{stableKey: "121.0.1.0"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.HighestSeen = tt.highest
+			if tt.signedAt != nil {
+				at := *tt.signedAt
+				cfg.MetadataSignedAt = &at
+			}
+
+			if changed := RecordHighestSeen(cfg, &tt.latest); changed != tt.wantChanged {
+				t.Fatalf("changed = %v, want %v", changed, tt.wantChanged)
+			}
+			if len(cfg.HighestSeen) != len(tt.wantHighest) {
+				t.Fatalf("HighestSeen = %v, want %v", cfg.HighestSeen, tt.wantHighest)
+			}
+			for key, want := range tt.wantHighest {
+				if cfg.HighestSeen[key] != want {
+					t.Fatalf("HighestSeen = %v, want %v", cfg.HighestSeen, tt.wantHighest)
+				}
+			}
+			switch {
+			case tt.wantSignedAt == nil && cfg.MetadataSignedAt != nil:
+				t.Fatalf("MetadataSignedAt = %v, want nil", cfg.MetadataSignedAt)
+			case tt.wantSignedAt != nil && (cfg.MetadataSignedAt == nil || !cfg.MetadataSignedAt.Equal(*tt.wantSignedAt)):
+				t.Fatalf("MetadataSignedAt = %v, want %v", cfg.MetadataSignedAt, *tt.wantSignedAt)
+			}
+		})
+	}
+}
+
+func TestRollbackAction(t *testing.T) {
+	tests := []struct {
+		name     string
+		action   string
+		wantCode int
+	}{
+		{name: "默认只警告", wantCode: ExitUpdated},
+		{name: "拒绝更新", action: RollbackRefuse, wantCode: ExitFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestInstall(t, func(cfg *Config) {
+				cfg.HighestSeen = map[string]string{getChromeDataKey("stable"): "122.0.1.0"}
+				cfg.RollbackAction = tt.action
+			})
+
+			if code := RunWithUI([]string{"--silent"}, &ScriptedUI{}); code != tt.wantCode {
+				t.Fatalf("退出码 = %d, want %d", code, tt.wantCode)
+			}
+			cfg := loadTestConfig(t)
+			if got := cfg.HighestSeen[getChromeDataKey("stable")]; got != "122.0.1.0" {
+				t.Fatalf("曾见过的最高版本 = %s，不应被较低的版本覆盖", got)
+			}
+			if updated := cfg.Version == testLatestChrome; updated != (tt.wantCode == ExitUpdated) {
+				t.Fatalf("Version = %s", cfg.Version)
+			}
+		})
+	}
+}
@@ -0,0 +1,453 @@
+package internal
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultServeAddr serve 命令默认监听地址
+const DefaultServeAddr = ":8780"
+
+// CacheServer 局域网缓存服务
+// 提供与上游格式相同的 data.json 和 chrome_plus.json，其中的下载地址指向本服务的 /files/，
+// 安装包在首次请求时从上游下载，按上游的大小和 SHA256（有时）校验后缓存到磁盘，之后支持 Range 的多线程下载
+type CacheServer struct {
+	Client  *MetadataClient    // 获取上游元数据的客户端
+	Dir     string             // 安装包缓存目录
+	Threads int                // 从上游下载的线程数
+	SignKey ed25519.PrivateKey // 签名私钥，设置后提供 data.json.sig 和 chrome_plus.json.sig
+
+	startedAt time.Time
+	requests  int64
+
+	mu      sync.Mutex
+	files   map[string]*cachedFile  // 文件名 -> 上游信息，只包含最近一次返回的元数据中的安装包
+	locks   map[string]*sync.Mutex  // 文件名 -> 下载锁，同一文件只从上游下载一次
+	fetched time.Time               // 最近一次获取上游元数据的时间
+	bodies  map[string][]servedBody // 元数据文件名 -> 最近返回的内容，签名针对其中之一
+
+	hashMu sync.Mutex
+	hashes map[string]fileHash // 文件名 -> 已计算的 SHA256，供节点间共享
+}
+
+// maxServedBodies 每个元数据文件保留的最近返回内容数，客户端获取元数据后按其 SHA256 请求签名
+const maxServedBodies = 8
+
+// servedBody 返回过的元数据内容
+type servedBody struct {
+	sha256 string
+	data   []byte
+}
+
+// cachedFile 可缓存的安装包
+type cachedFile struct {
+	Component string   `json:"component"`
+	Version   string   `json:"version"`
+	Size      int64    `json:"size"`
+	SHA256    string   `json:"sha256,omitempty"`
+	URLs      []string `json:"-"`
+}
+
+// NewCacheServer 创建局域网缓存服务
+func NewCacheServer(client *MetadataClient, dir string, threads int) *CacheServer {
+	return &CacheServer{
+		Client:    client,
+		Dir:       dir,
+		Threads:   threads,
+		startedAt: time.Now(),
+		files:     make(map[string]*cachedFile),
+		locks:     make(map[string]*sync.Mutex),
+		bodies:    make(map[string][]servedBody),
+		hashes:    make(map[string]fileHash),
+	}
+}
+
+// Handler 返回 HTTP 处理器
+func (c *CacheServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/"+chromeDataFile, c.handleChromeData)
+	mux.HandleFunc("/"+chromePlusRelFile, c.handleChromePlusRelease)
+	mux.HandleFunc("/files/", c.handleFile)
+	mux.HandleFunc("/"+chromeDataFile+".sig", c.handleSignature(chromeDataFile))
+	mux.HandleFunc("/"+chromePlusRelFile+".sig", c.handleSignature(chromePlusRelFile))
+	mux.HandleFunc("/peer/", c.handlePeerFile)
+	mux.HandleFunc("/status", c.handleStatus)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&c.requests, 1)
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// register 记录组件可缓存的安装包，替换该组件之前记录的安装包
+func (c *CacheServer) register(component string, files map[string]*cachedFile) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for name, file := range c.files {
+		if file.Component == component {
+			delete(c.files, name)
+		}
+	}
+	for name, file := range files {
+		c.files[name] = file
+	}
+	c.fetched = time.Now()
+}
+
+// Refresh 获取一次上游元数据，记录其中可缓存的安装包
+func (c *CacheServer) Refresh() error {
+	if _, err := c.chromeData(); err != nil {
+		return fmt.Errorf("获取 Chrome 数据失败: %w", err)
+	}
+	if _, err := c.chromePlusRelease(); err != nil {
+		return fmt.Errorf("获取 Chrome++ 信息失败: %w", err)
+	}
+	c.Client.Cache.Save()
+	return nil
+}
+
+// chromeData 获取上游 Chrome 数据，并将下载地址改为本服务的地址（上游的网络地址作为后备）
+func (c *CacheServer) chromeData() (ChromeData, error) {
+	dataURL := c.Client.chromeDataSource()
+	data, err := c.Client.fetchChromeData(dataURL)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(ChromeData, len(data))
+	files := make(map[string]*cachedFile)
+	for key, channel := range data {
+		upstream := c.Client.chromeURLs(dataURL, channel.URLs)
+		name := fmt.Sprintf("chrome_installer_%s.exe", channel.Version)
+		files[name] = &cachedFile{
+			Component: ComponentChrome,
+			Version:   channel.Version,
+			Size:      channel.Size,
+			SHA256:    channel.SHA256,
+			URLs:      upstream,
+		}
+		// 本服务的本地路径对其他机器无意义，不对外提供
+		channel.URLs = []string{"files/" + name}
+		for _, u := range upstream {
+			if !isFileURL(u) {
+				channel.URLs = append(channel.URLs, u)
+			}
+		}
+		result[key] = channel
+	}
+	c.register(ComponentChrome, files)
+	return result, nil
+}
+
+// chromePlusRelease 获取上游 Chrome++ Release，只保留压缩包，下载地址改为本服务的地址
+func (c *CacheServer) chromePlusRelease() (*GitHubRelease, error) {
+	release, base, err := c.Client.fetchChromePlusRelease()
+	if err != nil {
+		return nil, err
+	}
+	asset := chromePlusAsset(release)
+	if asset == nil {
+		return nil, fmt.Errorf("Chrome++ %s 中没有找到支持的压缩包", release.TagName)
+	}
+
+	name := fmt.Sprintf("chrome_plus_%s%s", release.TagName, chromePlusExt(asset.Name))
+	c.register(ComponentChromePlus, map[string]*cachedFile{name: {
+		Component: ComponentChromePlus,
+		Version:   release.TagName,
+		Size:      asset.Size,
+		SHA256:    asset.SHA256(),
+		URLs:      GitHubMirrorURLs(c.Client.GitHubMirrors, ResolveSource(base, asset.BrowserDownloadURL)),
+	}})
+	// 上游没有摘要时，已缓存的压缩包提供其 SHA256，客户端据此校验从本服务下载的文件
+	digest := asset.Digest
+	if asset.SHA256() == "" {
+		if sha := c.cachedSHA256(name, asset.Size); sha != "" {
+			digest = "sha256:" + sha
+		}
+	}
+	return &GitHubRelease{
+		TagName: release.TagName,
+		Assets:  []Asset{{Name: asset.Name, Size: asset.Size, BrowserDownloadURL: "files/" + name, Digest: digest}},
+	}, nil
+}
+
+func (c *CacheServer) handleChromeData(w http.ResponseWriter, r *http.Request) {
+	data, err := c.chromeData()
+	if err != nil {
+		http.Error(w, "获取 Chrome 数据失败: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	c.Client.Cache.Save()
+	c.writeMetadata(w, chromeDataFile, data)
+}
+
+func (c *CacheServer) handleChromePlusRelease(w http.ResponseWriter, r *http.Request) {
+	release, err := c.chromePlusRelease()
+	if err != nil {
+		http.Error(w, "获取 Chrome++ 信息失败: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+	c.Client.Cache.Save()
+	c.writeMetadata(w, chromePlusRelFile, release)
+}
+
+// writeMetadata 输出元数据并记录内容，供签名使用
+func (c *CacheServer) writeMetadata(w http.ResponseWriter, name string, v interface{}) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	c.recordBody(name, data)
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Write(data)
+}
+
+// recordBody 记录返回的元数据内容，每个文件只保留最近 maxServedBodies 个
+func (c *CacheServer) recordBody(name string, data []byte) {
+	sum := sha256.Sum256(data)
+	sha := hex.EncodeToString(sum[:])
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	bodies := c.bodies[name]
+	for i, body := range bodies {
+		if body.sha256 == sha {
+			bodies = append(bodies[:i], bodies[i+1:]...)
+			break
+		}
+	}
+	bodies = append(bodies, servedBody{sha256: sha, data: data})
+	if len(bodies) > maxServedBodies {
+		bodies = bodies[len(bodies)-maxServedBodies:]
+	}
+	c.bodies[name] = bodies
+}
+
+// servedBody 返回指定 SHA256 的已返回元数据内容，没有 SHA256 时返回最近一次的内容
+func (c *CacheServer) servedBody(name, sha string) []byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	bodies := c.bodies[name]
+	if sha == "" && len(bodies) > 0 {
+		return bodies[len(bodies)-1].data
+	}
+	for _, body := range bodies {
+		if body.sha256 == sha {
+			return body.data
+		}
+	}
+	return nil
+}
+
+// handleSignature 返回元数据签名的处理器
+// 客户端通过 ?sha256= 指定所获取的元数据内容，签名只针对该内容；未返回过该内容时返回 404
+func (c *CacheServer) handleSignature(name string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if c.SignKey == nil {
+			http.NotFound(w, r)
+			return
+		}
+
+		body := c.servedBody(name, strings.ToLower(r.URL.Query().Get("sha256")))
+		if body == nil {
+			http.Error(w, "没有返回过对应的 "+name+"，请重新获取元数据", http.StatusNotFound)
+			return
+		}
+		sig, err := SignPayload(c.SignKey, body, time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Write(sig)
+	}
+}
+
+// handleFile 提供安装包下载，未缓存时先从上游下载并按上游信息校验
+func (c *CacheServer) handleFile(w http.ResponseWriter, r *http.Request) {
+	name := path.Base(r.URL.Path)
+	if name != strings.TrimPrefix(r.URL.Path, "/files/") || name == "." || name == "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	local, err := c.ensureFile(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	if local == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	file, err := os.Open(local)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	// ServeContent 处理 Range、HEAD 和 If-Modified-Since
+	http.ServeContent(w, r, name, info.ModTime(), file)
+}
+
+// lookup 查找最近一次返回的元数据中的安装包，不在其中时返回 nil（不为未知的文件名请求上游）
+func (c *CacheServer) lookup(name string) *cachedFile {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.files[name]
+}
+
+// ensureFile 确保安装包已缓存并与上游的大小和 SHA256（有时）一致，返回本地路径
+// 只提供最近一次返回的元数据中的安装包，其他文件名返回空路径
+func (c *CacheServer) ensureFile(name string) (string, error) {
+	local := filepath.Join(c.Dir, name)
+	file := c.lookup(name)
+	if file == nil {
+		return "", nil
+	}
+
+	// 同一文件只从上游下载一次，其他请求等待下载完成
+	c.mu.Lock()
+	lock := c.locks[name]
+	if lock == nil {
+		lock = &sync.Mutex{}
+		c.locks[name] = lock
+	}
+	c.mu.Unlock()
+	lock.Lock()
+	defer lock.Unlock()
+
+	if fileExists(local) {
+		if err := VerifyFile(local, file.Size, file.SHA256); err == nil {
+			return local, nil
+		}
+		os.Remove(local)
+	}
+
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return "", err
+	}
+	logf("从上游下载 %s %s", componentName(file.Component), file.Version)
+	if err := DownloadVerified(file.URLs, local, file.Size, file.SHA256, c.Threads, progressPrinter(name, true)); err != nil {
+		return "", fmt.Errorf("从上游下载 %s 失败: %w", name, err)
+	}
+	logf("%s 已缓存", name)
+	return local, nil
+}
+
+// serveStatus /status 响应结构
+type serveStatus struct {
+	Version   string       `json:"version"`
+	StartedAt time.Time    `json:"started_at"`
+	Requests  int64        `json:"requests"`
+	FetchedAt *time.Time   `json:"fetched_at,omitempty"`
+	Files     []fileStatus `json:"files"`
+}
+
+// fileStatus 单个安装包的缓存状态
+type fileStatus struct {
+	Name string `json:"name"`
+	cachedFile
+	Cached bool `json:"cached"`
+}
+
+func (c *CacheServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	status := serveStatus{
+		Version:   VersionString(),
+		StartedAt: c.startedAt,
+		Requests:  atomic.LoadInt64(&c.requests),
+		Files:     []fileStatus{},
+	}
+
+	c.mu.Lock()
+	if !c.fetched.IsZero() {
+		fetched := c.fetched
+		status.FetchedAt = &fetched
+	}
+	for name, file := range c.files {
+		status.Files = append(status.Files, fileStatus{
+			Name:       name,
+			cachedFile: *file,
+			Cached:     fileExists(filepath.Join(c.Dir, name)),
+		})
+	}
+	c.mu.Unlock()
+
+	sort.Slice(status.Files, func(i, j int) bool { return status.Files[i].Name < status.Files[j].Name })
+	writeJSON(w, status)
+}
+
+// writeJSON 输出 JSON 响应
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// serve 运行局域网缓存服务，直到进程退出
+func (s *session) serve(addr string) int {
+	client := NewMetadataClient(s.cfg)
+	server := NewCacheServer(client, filepath.Join(programDir(), "serve_cache"), s.cfg.GetThreads())
+	if s.opts.Key != "" {
+		key, err := LoadPrivateKey(s.opts.Key)
+		if err != nil {
+			return s.fail("读取私钥失败: " + err.Error())
+		}
+		server.SignKey = key
+	}
+
+	// 先获取一次元数据，服务重启后使用缓存元数据的客户端仍可下载安装包
+	if err := server.Refresh(); err != nil {
+		s.warn("获取上游元数据失败: " + err.Error())
+	}
+
+	// 应答局域网节点发现，共享已缓存的安装包
+	if s.cfg.PeerDiscovery {
+		port, _ := strconv.Atoi(strings.TrimPrefix(portSuffix(addr), ":"))
+		conn, err := net.ListenUDP("udp4", &net.UDPAddr{Port: peerDiscoveryPort})
+		if err != nil {
+			return s.fail("节点发现启动失败: " + err.Error())
+		}
+		defer conn.Close()
+		go answerPeers(conn, port, server.packageList)
+		fmt.Printf("已启用局域网节点发现 (UDP %d)\n", peerDiscoveryPort)
+	}
+
+	fmt.Printf("ChromeGo 缓存服务已启动: http://%s/\n", addr)
+	fmt.Printf("其他机器可将 chrome_data_url 设为 http://<本机地址>%s/%s，chrome_plus_release_url 设为 http://<本机地址>%s/%s\n",
+		portSuffix(addr), chromeDataFile, portSuffix(addr), chromePlusRelFile)
+	if err := http.ListenAndServe(addr, server.Handler()); err != nil {
+		return s.fail("缓存服务启动失败: " + err.Error())
+	}
+	return ExitUpToDate
+}
+
+// portSuffix 返回监听地址中的 ":端口" 部分
+func portSuffix(addr string) string {
+	if idx := strings.LastIndex(addr, ":"); idx >= 0 {
+		return addr[idx:]
+	}
+	return ""
+}
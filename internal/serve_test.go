@@ -0,0 +1,136 @@
+package internal
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// newTestCacheServer 以 setupTestInstall 的本地数据源为上游启动缓存服务
+func newTestCacheServer(t *testing.T, key ed25519.PrivateKey) (*CacheServer, *httptest.Server, string) {
+	t.Helper()
+	dir := setupTestInstall(t, nil)
+	client := NewMetadataClient(loadTestConfig(t))
+	client.MinInterval = 0
+	server := NewCacheServer(client, filepath.Join(dir, "serve_cache"), 1)
+	server.SignKey = key
+	ts := httptest.NewServer(server.Handler())
+	t.Cleanup(ts.Close)
+	return server, ts, dir
+}
+
+// httpGetTest 返回响应状态码和内容
+func httpGetTest(t *testing.T, url string) (int, []byte) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, body
+}
+
+func TestCacheServerFiles(t *testing.T) {
+	server, ts, dir := newTestCacheServer(t, nil)
+
+	// 未知的文件名不请求上游
+	if code, _ := httpGetTest(t, ts.URL+"/files/chrome_installer_"+testLatestChrome+".exe"); code != http.StatusNotFound {
+		t.Fatalf("未获取元数据时状态码 = %d, want 404", code)
+	}
+	if !server.fetched.IsZero() {
+		t.Fatal("未知的文件名不应获取上游元数据")
+	}
+
+	if code, body := httpGetTest(t, ts.URL+"/"+chromeDataFile); code != http.StatusOK {
+		t.Fatalf("data.json 状态码 = %d: %s", code, body)
+	}
+	code, body := httpGetTest(t, ts.URL+"/files/chrome_installer_"+testLatestChrome+".exe")
+	if code != http.StatusOK {
+		t.Fatalf("安装包状态码 = %d: %s", code, body)
+	}
+	if want := readTestFile(t, filepath.Join(dir, "source", "chrome.zip")); string(body) != want {
+		t.Fatal("安装包内容与上游不一致")
+	}
+
+	for _, name := range []string{"chrome_installer_1.0.0.0.exe", "config.json", "chrome_plus_v0.1.0.zip"} {
+		if code, _ := httpGetTest(t, ts.URL+"/files/"+name); code != http.StatusNotFound {
+			t.Fatalf("%s 状态码 = %d, want 404", name, code)
+		}
+	}
+}
+
+func TestCacheServerSignature(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ts, dir := newTestCacheServer(t, priv)
+
+	_, first := httpGetTest(t, ts.URL+"/"+chromeDataFile)
+
+	// 上游更新后再返回一次，签名仍可针对之前获取的内容
+	dataPath := filepath.Join(dir, "source", chromeDataFile)
+	writeTestJSON(t, dataPath, ChromeData{getChromeDataKey("stable"): {Version: "122.0.1.0", URLs: []string{"chrome.zip"}}})
+	modTime := time.Now().Add(time.Hour)
+	if err := os.Chtimes(dataPath, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+	_, second := httpGetTest(t, ts.URL+"/"+chromeDataFile)
+	if string(first) == string(second) {
+		t.Fatal("上游更新后 data.json 应变化")
+	}
+	if code, _ := httpGetTest(t, ts.URL+"/files/chrome_installer_"+testLatestChrome+".exe"); code != http.StatusNotFound {
+		t.Fatalf("不在当前元数据中的安装包状态码 = %d, want 404", code)
+	}
+
+	for _, body := range [][]byte{first, second} {
+		code, sig := httpGetTest(t, bodySignatureURL(ts.URL+"/"+chromeDataFile, body))
+		if code != http.StatusOK {
+			t.Fatalf("签名状态码 = %d: %s", code, sig)
+		}
+		if _, err := VerifySignature([]ed25519.PublicKey{pub}, body, sig); err != nil {
+			t.Fatalf("签名应针对所请求的内容: %v", err)
+		}
+	}
+
+	sum := sha256.Sum256([]byte("never served"))
+	if code, _ := httpGetTest(t, ts.URL+"/"+chromeDataFile+".sig?sha256="+hex.EncodeToString(sum[:])); code != http.StatusNotFound {
+		t.Fatalf("未返回过的内容状态码 = %d, want 404", code)
+	}
+}
+
+func TestCacheServerSignedClient(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, ts, _ := newTestCacheServer(t, priv)
+
+	client := &MetadataClient{
+		ChromeDataURL:        ts.URL + "/" + chromeDataFile,
+		ChromePlusReleaseURL: ts.URL + "/" + chromePlusRelFile,
+		TrustedKeys:          []ed25519.PublicKey{pub},
+		RequireSignature:     true,
+	}
+	info, err := client.GetLatestVersion("stable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ChromeVersion != testLatestChrome || info.ChromePlusVersion != testLatestChromePlus {
+		t.Fatalf("版本 = %s / %s (%s)", info.ChromeVersion, info.ChromePlusVersion, info.ChromePlusError)
+	}
+	if info.ChromePlusSHA256 == "" {
+		t.Fatal("应带上 Chrome++ 的 SHA256")
+	}
+}
@@ -0,0 +1,208 @@
+package internal
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Signature 分离式签名（.sig 文件）
+// 签名内容为元数据原文、换行符和签名时间（RFC 3339，UTC），签名时间同时受签名保护
+type Signature struct {
+	SignedAt  time.Time `json:"signed_at"`
+	Signature string    `json:"signature"` // base64 编码的 ed25519 签名
+}
+
+// signedMessage 构造签名内容
+func signedMessage(payload []byte, signedAt time.Time) []byte {
+	msg := make([]byte, 0, len(payload)+32)
+	msg = append(msg, payload...)
+	msg = append(msg, '\n')
+	msg = append(msg, signedAt.UTC().Format(time.RFC3339)...)
+	return msg
+}
+
+// SignPayload 对元数据签名，返回 .sig 文件内容
+func SignPayload(key ed25519.PrivateKey, payload []byte, now time.Time) ([]byte, error) {
+	signedAt := now.UTC().Truncate(time.Second)
+	sig := Signature{
+		SignedAt:  signedAt,
+		Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, signedMessage(payload, signedAt))),
+	}
+	return json.MarshalIndent(sig, "", "  ")
+}
+
+// VerifySignature 使用受信任的公钥校验元数据签名，任一公钥校验通过即可
+func VerifySignature(keys []ed25519.PublicKey, payload, sigData []byte) (*Signature, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("未配置受信任的公钥 (trusted_keys)")
+	}
+	var sig Signature
+	if err := json.Unmarshal(sigData, &sig); err != nil {
+		return nil, fmt.Errorf("签名格式无效: %w", err)
+	}
+	raw, err := base64.StdEncoding.DecodeString(sig.Signature)
+	if err != nil || len(raw) != ed25519.SignatureSize {
+		return nil, errors.New("签名格式无效")
+	}
+	msg := signedMessage(payload, sig.SignedAt)
+	for _, key := range keys {
+		if ed25519.Verify(key, msg, raw) {
+			return &sig, nil
+		}
+	}
+	return nil, errors.New("签名校验失败：元数据可能被篡改，或签名密钥不受信任")
+}
+
+// ParsePublicKeys 解析 base64 编码的 ed25519 公钥
+func ParsePublicKeys(list []string) ([]ed25519.PublicKey, error) {
+	var keys []ed25519.PublicKey
+	for _, s := range list {
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+		if err != nil || len(raw) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("无效的公钥: %s", s)
+		}
+		keys = append(keys, ed25519.PublicKey(raw))
+	}
+	return keys, nil
+}
+
+// LoadPrivateKey 读取 base64 编码的 ed25519 私钥文件
+func LoadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("私钥格式无效: %w", err)
+	}
+	switch len(raw) {
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(raw), nil
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(raw), nil
+	default:
+		return nil, errors.New("私钥格式无效")
+	}
+}
+
+// GenerateKey 生成 ed25519 密钥对，私钥写入文件，返回 base64 编码的公钥
+func GenerateKey(path string) (string, error) {
+	if fileExists(path) {
+		return "", fmt.Errorf("私钥文件已存在: %s", path)
+	}
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(priv)+"\n"), 0600); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(pub), nil
+}
+
+// SignFile 对文件签名，签名写入同目录下的 <文件名>.sig
+func SignFile(key ed25519.PrivateKey, path string) error {
+	payload, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	sig, err := SignPayload(key, payload, time.Now())
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path+".sig", sig, 0644)
+}
+
+// signatureURL 返回元数据对应的签名地址
+func signatureURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL + ".sig"
+	}
+	u.Path += ".sig"
+	u.RawPath = ""
+	return u.String()
+}
+
+// bodySignatureURL 返回指定元数据内容对应的签名地址
+// http(s) 地址附带内容的 SHA256，serve 命令据此对客户端获取到的那份内容签名，静态文件服务器会忽略该参数
+func bodySignatureURL(rawURL string, body []byte) string {
+	sigURL := signatureURL(rawURL)
+	u, err := url.Parse(sigURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return sigURL
+	}
+	sum := sha256.Sum256(body)
+	q := u.Query()
+	q.Set("sha256", hex.EncodeToString(sum[:]))
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// fetchMetadata 获取元数据；signed 为 true 时同时获取 .sig 签名并校验，校验失败返回错误
+func (m *MetadataClient) fetchMetadata(rawURL string, header http.Header, signed bool) ([]byte, error) {
+	body, err := m.fetchGitHub(rawURL, header)
+	if err != nil || !signed {
+		return body, err
+	}
+
+	sigData, err := m.Fetch(bodySignatureURL(rawURL, body), nil)
+	if err != nil {
+		return nil, fmt.Errorf("获取签名失败（数据源要求签名）: %w", err)
+	}
+	if _, err := VerifySignature(m.TrustedKeys, body, sigData); err != nil {
+		if m.Bypass {
+			return nil, err
+		}
+		// 缓存中的元数据和签名可能不是同一版本，忽略缓存重新获取一次
+		fresh := *m
+		fresh.Bypass = true
+		return fresh.fetchMetadata(rawURL, header, signed)
+	}
+	return body, nil
+}
+
+// sign 执行 sign 命令：生成密钥对，或对元数据文件签名
+func (s *session) sign() int {
+	opts := s.opts
+	if opts.Key == "" {
+		return s.fail("需要指定私钥文件: sign --key <私钥文件> <文件>...")
+	}
+
+	if opts.Keygen {
+		pub, err := GenerateKey(opts.Key)
+		if err != nil {
+			return s.fail("生成密钥失败: " + err.Error())
+		}
+		fmt.Printf("私钥已保存到 %s，请妥善保管\n", opts.Key)
+		fmt.Printf("公钥（添加到 config.json 的 trusted_keys 中）:\n%s\n", pub)
+		return ExitUpToDate
+	}
+
+	if len(opts.Args) == 0 {
+		return s.fail("需要指定要签名的文件: sign --key <私钥文件> <文件>...")
+	}
+	key, err := LoadPrivateKey(opts.Key)
+	if err != nil {
+		return s.fail("读取私钥失败: " + err.Error())
+	}
+	for _, path := range opts.Args {
+		if err := SignFile(key, path); err != nil {
+			return s.fail(fmt.Sprintf("签名 %s 失败: %v", path, err))
+		}
+		fmt.Printf("已签名: %s.sig\n", path)
+	}
+	return ExitUpToDate
+}
@@ -0,0 +1,143 @@
+package internal
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// signTestFile 使用私钥对文件签名，签名时间为 signedAt
+func signTestFile(t *testing.T, key ed25519.PrivateKey, path string, signedAt time.Time) []byte {
+	t.Helper()
+	sig, err := SignPayload(key, []byte(readTestFile(t, path)), signedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sig
+}
+
+func TestRequireSignature(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, untrusted, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signedAt := time.Date(2026, 10, 1, 8, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		sig       func(t *testing.T, path string) []byte // 返回 data.json 的签名，nil 表示不提供签名
+		wantError string                                 // 为空表示校验通过
+	}{
+		{
+			name: "受信任的签名",
+			sig:  func(t *testing.T, path string) []byte { return signTestFile(t, priv, path, signedAt) },
+		},
+		{
+			name:      "没有签名",
+			sig:       func(*testing.T, string) []byte { return nil },
+			wantError: "获取签名失败",
+		},
+		{
+			name: "签名格式无效",
+			sig: func(*testing.T, string) []byte {
+				return []byte(`{"signed_at":"2026-10-01T08:00:00Z","signature":"bm90IGEgc2lnbmF0dXJl"}`)
+			},
+			wantError: "签名格式无效",
+		},
+		{
+			name: "签名针对其他内容",
+			sig: func(t *testing.T, _ string) []byte {
+				sig, err := SignPayload(priv, []byte(`{"win_stable_x64":{"version":"1.0.0.0"}}`), signedAt)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return sig
+			},
+			wantError: "签名校验失败",
+		},
+		{
+			name:      "签名密钥不受信任",
+			sig:       func(t *testing.T, path string) []byte { return signTestFile(t, untrusted, path, signedAt) },
+			wantError: "签名校验失败",
+		},
+		{
+			name: "签名时间被修改",
+			sig: func(t *testing.T, path string) []byte {
+				var sig Signature
+				if err := json.Unmarshal(signTestFile(t, priv, path, signedAt), &sig); err != nil {
+					t.Fatal(err)
+				}
+				sig.SignedAt = signedAt.Add(24 * time.Hour)
+				data, err := json.Marshal(sig)
+				if err != nil {
+					t.Fatal(err)
+				}
+				return data
+			},
+			wantError: "签名校验失败",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := setupTestInstall(t, func(cfg *Config) {
+				cfg.TrustedKeys = []string{base64.StdEncoding.EncodeToString(pub)}
+				cfg.RequireSignature = true
+			})
+			source := filepath.Join(dir, "source")
+			plusPath := filepath.Join(source, chromePlusRelFile)
+			writeTestFile(t, plusPath+".sig", string(signTestFile(t, priv, plusPath, signedAt)))
+			dataPath := filepath.Join(source, chromeDataFile)
+			if sig := tt.sig(t, dataPath); sig != nil {
+				writeTestFile(t, dataPath+".sig", string(sig))
+			}
+
+			info, err := NewMetadataClient(loadTestConfig(t)).GetLatestVersion("stable")
+			if tt.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+					t.Fatalf("err = %v, want %q", err, tt.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !info.MetadataSigned || !info.MetadataTime.Equal(signedAt) {
+				t.Fatalf("MetadataSigned = %v, MetadataTime = %v, want 签名时间 %v", info.MetadataSigned, info.MetadataTime, signedAt)
+			}
+			if info.ChromePlusVersion != testLatestChromePlus {
+				t.Fatalf("ChromePlusVersion = %q (%s)", info.ChromePlusVersion, info.ChromePlusError)
+			}
+		})
+	}
+}
+
+func TestRequireSignatureChromePlus(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := setupTestInstall(t, func(cfg *Config) {
+		cfg.TrustedKeys = []string{base64.StdEncoding.EncodeToString(pub)}
+		cfg.RequireSignature = true
+	})
+	dataPath := filepath.Join(dir, "source", chromeDataFile)
+	writeTestFile(t, dataPath+".sig", string(signTestFile(t, priv, dataPath, time.Now())))
+
+	// Chrome++ 元数据没有签名时只放弃 Chrome++ 的更新
+	info, err := NewMetadataClient(loadTestConfig(t)).GetLatestVersion("stable")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ChromeVersion != testLatestChrome || info.ChromePlusVersion != "" || !strings.Contains(info.ChromePlusError, "获取签名失败") {
+		t.Fatalf("版本 = %s / %s, ChromePlusError = %q", info.ChromeVersion, info.ChromePlusVersion, info.ChromePlusError)
+	}
+}
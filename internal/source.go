@@ -0,0 +1,139 @@
+package internal
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// 本地元数据源为目录时使用的默认文件名
+const (
+	chromeDataFile    = "data.json"        // Chrome 版本数据，格式同 chrome_installer 的 data.json
+	chromePlusRelFile = "chrome_plus.json" // Chrome++ Release 信息，格式同 GitHub Release API
+)
+
+// newBaseTransport 创建底层传输层：在默认传输层的基础上支持 file:// 地址
+// 本地文件同样支持 HEAD、Range 和 If-Modified-Since，下载和校验与 HTTP 走相同流程
+func newBaseTransport() http.RoundTripper {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.RegisterProtocol("file", fileTransport{})
+	return t
+}
+
+// fileTransport 读取 file:// 地址指向的本地文件或网络共享
+type fileTransport struct{}
+
+func (fileTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return http.NewFileTransport(localFS{host: req.URL.Host}).RoundTrip(req)
+}
+
+// localFS 将 file:// 地址的路径映射为本地路径
+type localFS struct {
+	host string // 非空且不是 localhost 时表示网络共享主机（\\host\share）
+}
+
+func (fs localFS) Open(name string) (http.File, error) {
+	return os.Open(localPath(fs.host, name))
+}
+
+// localPath 将 file:// 地址的主机和路径转换为本地路径
+func localPath(host, p string) string {
+	if host != "" && !strings.EqualFold(host, "localhost") {
+		return `\\` + host + filepath.FromSlash(p)
+	}
+	// file:///C:/dir/file → C:/dir/file
+	if len(p) >= 3 && p[0] == '/' && isDriveLetter(p[1]) && p[2] == ':' {
+		p = p[1:]
+	}
+	return filepath.FromSlash(p)
+}
+
+// isDriveLetter 判断是否为 Windows 盘符字母
+func isDriveLetter(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+// isLocalPath 判断是否为本地路径（盘符路径、UNC 路径或绝对路径）
+func isLocalPath(s string) bool {
+	if len(s) >= 2 && isDriveLetter(s[0]) && s[1] == ':' {
+		return true
+	}
+	return strings.HasPrefix(s, `\\`) || filepath.IsAbs(s)
+}
+
+// fileURL 将本地路径转换为 file:// 地址
+func fileURL(p string) string {
+	if strings.HasPrefix(p, `\\`) {
+		// UNC 路径：\\host\share\file → file://host/share/file
+		rest := strings.TrimPrefix(strings.ReplaceAll(p, `\`, "/"), "//")
+		host, path, _ := strings.Cut(rest, "/")
+		return (&url.URL{Scheme: "file", Host: host, Path: "/" + path}).String()
+	}
+	p = strings.ReplaceAll(filepath.ToSlash(p), `\`, "/")
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	return (&url.URL{Scheme: "file", Path: p}).String()
+}
+
+// ResolveSource 将数据源解析为可请求的地址
+// 支持 http(s)://、file:// 地址和本地路径（含网络共享）；相对地址基于 base 解析，base 为空时基于程序目录
+func ResolveSource(base, ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return ""
+	}
+	if isLocalPath(ref) {
+		return fileURL(ref)
+	}
+	if u, err := url.Parse(ref); err == nil && u.Scheme != "" {
+		return ref
+	}
+
+	if base == "" {
+		return fileURL(filepath.Join(programDir(), ref))
+	}
+	b, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+	r, err := url.Parse(strings.ReplaceAll(ref, `\`, "/"))
+	if err != nil {
+		return ref
+	}
+	return b.ResolveReference(r).String()
+}
+
+// metadataSource 解析元数据源地址；本地源为目录时使用目录下的默认文件
+func metadataSource(raw, defaultName string) string {
+	source := ResolveSource("", raw)
+	u, err := url.Parse(source)
+	if err != nil || u.Scheme != "file" {
+		return source
+	}
+	if info, err := os.Stat(localPath(u.Host, u.Path)); err == nil && info.IsDir() {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + defaultName
+		return u.String()
+	}
+	return source
+}
+
+// sameHost 判断两个地址的主机是否相同
+func sameHost(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+	return ua.Host != "" && strings.EqualFold(ua.Host, ub.Host)
+}
+
+// isFileURL 判断是否为 file:// 地址
+func isFileURL(rawURL string) bool {
+	return strings.HasPrefix(strings.ToLower(rawURL), "file://")
+}
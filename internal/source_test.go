@@ -0,0 +1,193 @@
+package internal
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestResolveSource(t *testing.T) {
+	dir := t.TempDir()
+	oldDir := programDir
+	programDir = func() string { return dir }
+	t.Cleanup(func() { programDir = oldDir })
+
+	tests := []struct {
+		name string
+		base string
+		ref  string
+		want string
+	}{
+		{name: "空地址", ref: " ", want: ""},
+		{name: "绝对路径", ref: filepath.Join(dir, "source", "data.json"), want: fileURL(filepath.Join(dir, "source", "data.json"))},
+		{name: "网络共享", ref: `\\nas\chrome\data.json`, want: "file://nas/chrome/data.json"},
+		{name: "http 地址不变", base: "file:///mnt/usb/data.json", ref: "https://dl.google.com/chrome.exe", want: "https://dl.google.com/chrome.exe"},
+		{name: "相对 file:// 数据源", base: "file:///mnt/usb/data.json", ref: "files/chrome.exe", want: "file:///mnt/usb/files/chrome.exe"},
+		{name: "相对网络共享中的数据源", base: "file://nas/chrome/data.json", ref: `files\chrome.exe`, want: "file://nas/chrome/files/chrome.exe"},
+		{name: "相对 http 数据源", base: "http://cache.lan:8080/data.json", ref: "files/chrome.exe", want: "http://cache.lan:8080/files/chrome.exe"},
+		{name: "没有基准地址时基于程序目录", ref: "offline", want: fileURL(filepath.Join(dir, "offline"))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ResolveSource(tt.base, tt.ref); got != tt.want {
+				t.Fatalf("ResolveSource(%q, %q) = %q, want %q", tt.base, tt.ref, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLocalPath(t *testing.T) {
+	tests := []struct {
+		host string
+		path string
+		want string
+	}{
+		{path: "/mnt/usb/data.json", want: filepath.FromSlash("/mnt/usb/data.json")},
+		{host: "localhost", path: "/mnt/usb/data.json", want: filepath.FromSlash("/mnt/usb/data.json")},
+		{path: "/C:/chrome/data.json", want: filepath.FromSlash("C:/chrome/data.json")},
+		{host: "nas", path: "/chrome/data.json", want: `\\nas` + filepath.FromSlash("/chrome/data.json")},
+	}
+	for _, tt := range tests {
+		if got := localPath(tt.host, tt.path); got != tt.want {
+			t.Errorf("localPath(%q, %q) = %q, want %q", tt.host, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestMetadataSource(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, "custom.json"), "{}")
+
+	if got, want := metadataSource(dir, chromeDataFile), fileURL(filepath.Join(dir, chromeDataFile)); got != want {
+		t.Fatalf("目录数据源 = %q, want %q", got, want)
+	}
+	if got, want := metadataSource(filepath.Join(dir, "custom.json"), chromeDataFile), fileURL(filepath.Join(dir, "custom.json")); got != want {
+		t.Fatalf("文件数据源 = %q, want %q", got, want)
+	}
+	if got := metadataSource("https://cache.lan/", chromeDataFile); got != "https://cache.lan/" {
+		t.Fatalf("http 数据源 = %q", got)
+	}
+}
+
+func TestChromeURLsOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		dataURL string
+		rules   []URLRewrite
+		urls    []string
+		want    []string
+	}{
+		{
+			name:    "本地文件优先，其次按主机优先级，忽略 http",
+			dataURL: "file:///mnt/usb/data.json",
+			urls: []string{
+				"https://edgedl.me.gvt1.com/chrome.exe",
+				"http://dl.google.com/chrome.exe",
+				"https://www.google.com/chrome.exe",
+				"https://dl.google.com/chrome.exe",
+				"chrome.exe",
+			},
+			want: []string{
+				"file:///mnt/usb/chrome.exe",
+				"https://dl.google.com/chrome.exe",
+				"https://www.google.com/chrome.exe",
+				"https://edgedl.me.gvt1.com/chrome.exe",
+			},
+		},
+		{
+			name:    "与数据源同源的地址最先且允许 http，其次是改写的地址",
+			dataURL: "http://cache.lan:8080/data.json",
+			rules:   []URLRewrite{{Host: "dl.google.com", To: "http://mirror.lan"}},
+			urls: []string{
+				"https://dl.google.com/chrome.exe",
+				"files/chrome.exe",
+			},
+			want: []string{
+				"http://cache.lan:8080/files/chrome.exe",
+				"http://mirror.lan/chrome.exe",
+				"https://dl.google.com/chrome.exe",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &MetadataClient{URLRewrites: tt.rules}
+			got := m.chromeURLs(tt.dataURL, tt.urls)
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Fatalf("chromeURLs =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestLocalSourceDownload(t *testing.T) {
+	dir := t.TempDir()
+	content := strings.Repeat("chrome installer ", 8192)
+	src := filepath.Join(dir, "share", "chrome.exe")
+	writeTestFile(t, src, content)
+	sha := sha256Hex(content)
+	missing := fileURL(filepath.Join(dir, "usb", "chrome.exe"))
+
+	tests := []struct {
+		name string
+		urls []string
+	}{
+		{name: "多线程读取本地文件", urls: []string{fileURL(src)}},
+		{name: "第一个本地源不存在", urls: []string{missing, fileURL(src)}},
+		{name: "本地源不存在时使用 HTTP 源", urls: []string{missing, newDownloadServer(t, content, true, 0)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := filepath.Join(t.TempDir(), "chrome.exe")
+			var mu sync.Mutex
+			var reported int64 // 报告的最大进度
+			progress := func(downloaded, total int64) {
+				mu.Lock()
+				defer mu.Unlock()
+				if downloaded > reported {
+					reported = downloaded
+				}
+			}
+			if err := DownloadVerified(tt.urls, dest, int64(len(content)), sha, 4, progress); err != nil {
+				t.Fatal(err)
+			}
+			if got := readTestFile(t, dest); got != content {
+				t.Fatal("下载的内容不一致")
+			}
+			if reported != int64(len(content)) {
+				t.Fatalf("进度 = %d, want %d", reported, len(content))
+			}
+		})
+	}
+
+	if err := DownloadVerified([]string{missing}, filepath.Join(dir, "missing.exe"), 0, sha, 1, nil); err == nil {
+		t.Fatal("所有源都不可用时应失败")
+	}
+}
+
+func TestUpdateFallsBackToNextSource(t *testing.T) {
+	dir := setupTestInstall(t, nil)
+
+	// data.json 中的第一个地址不可用（如已拔出的 U 盘）
+	dataPath := filepath.Join(dir, "source", chromeDataFile)
+	var data ChromeData
+	if err := json.Unmarshal([]byte(readTestFile(t, dataPath)), &data); err != nil {
+		t.Fatal(err)
+	}
+	entry := data[getChromeDataKey("stable")]
+	entry.URLs = append([]string{filepath.Join(dir, "unplugged", "chrome.zip")}, entry.URLs...)
+	data[getChromeDataKey("stable")] = entry
+	writeTestJSON(t, dataPath, data)
+
+	if code := RunWithUI([]string{"--silent"}, &ScriptedUI{}); code != ExitUpdated {
+		t.Fatalf("退出码 = %d, want %d", code, ExitUpdated)
+	}
+	if got := readTestFile(t, filepath.Join(dir, "App", "chrome.exe")); got != "new chrome" {
+		t.Fatalf("chrome.exe = %q", got)
+	}
+}
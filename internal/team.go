@@ -0,0 +1,132 @@
+package internal
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// teamPolicyFile 团队策略地址为目录时读取的文件名
+const teamPolicyFile = "policy.json"
+
+// TeamPolicy 团队策略，由管理员统一发布，约束各机器可更新的版本
+type TeamPolicy struct {
+	Channels           map[string]ChannelPolicy `json:"channels"`             // 按通道（stable/beta/dev/canary）批准的 Chrome 版本
+	ChromePlusTag      string                   `json:"chrome_plus_tag"`      // 批准的 Chrome++ 版本，为空表示不限制
+	ChromePlusDeadline *time.Time               `json:"chrome_plus_deadline"` // 超过该时间后强制更新到批准的 Chrome++ 版本
+	AllowSkip          *bool                    `json:"allow_skip"`           // 是否允许跳过版本，为空表示允许
+	Enforce            json.RawMessage          `json:"enforce"`              // 强制覆盖的本地配置项，格式与 config.json 相同
+}
+
+// ChannelPolicy 单个通道批准的 Chrome 版本
+type ChannelPolicy struct {
+	Version    string     `json:"version"`     // 批准的版本，只允许更新到该版本
+	MinVersion string     `json:"min_version"` // 最低版本，已安装版本低于该版本时强制更新
+	MaxVersion string     `json:"max_version"` // 允许的最高版本
+	Deadline   *time.Time `json:"deadline"`    // 超过该时间后强制更新到批准的版本
+}
+
+// FetchTeamPolicy 获取团队策略，要求签名时同时校验签名
+func (m *MetadataClient) FetchTeamPolicy(rawURL string) (*TeamPolicy, error) {
+	body, err := m.fetchMetadata(metadataSource(rawURL, teamPolicyFile), nil, m.RequireSignature)
+	if err != nil {
+		return nil, err
+	}
+	var policy TeamPolicy
+	if err := json.Unmarshal(body, &policy); err != nil {
+		return nil, fmt.Errorf("团队策略格式无效: %w", err)
+	}
+	return &policy, nil
+}
+
+// channel 获取通道的版本策略，未配置时返回 nil
+func (t *TeamPolicy) channel(name string) *ChannelPolicy {
+	if t == nil {
+		return nil
+	}
+	if c, ok := t.Channels[chromeChannelName(name)]; ok {
+		return &c
+	}
+	return nil
+}
+
+// allowSkip 是否允许跳过版本
+func (t *TeamPolicy) allowSkip() bool {
+	return t == nil || t.AllowSkip == nil || *t.AllowSkip
+}
+
+// chromeHold 最新 Chrome 版本未经批准时返回原因，否则返回空字符串
+func (t *TeamPolicy) chromeHold(channel, latest string) string {
+	c := t.channel(channel)
+	switch {
+	case c == nil:
+		return ""
+	case c.Version != "" && c.Version != latest:
+		// data.json 只提供各通道的最新版本，批准的版本不是最新版本时无法从数据源获取
+		return fmt.Sprintf("团队批准的版本为 %s，数据源中没有该版本（只提供最新版本 %s）", c.Version, latest)
+	case c.MaxVersion != "" && CompareVersion(c.MaxVersion, latest):
+		return fmt.Sprintf("超过团队允许的最高版本 %s", c.MaxVersion)
+	}
+	return ""
+}
+
+// chromeUrgency 团队策略要求 Chrome 必须更新时返回原因，否则返回空字符串
+func (t *TeamPolicy) chromeUrgency(channel, installed string, now time.Time) string {
+	c := t.channel(channel)
+	switch {
+	case c == nil || installed == "":
+		return ""
+	case c.MinVersion != "" && CompareVersion(installed, c.MinVersion):
+		return fmt.Sprintf("低于团队要求的最低版本 %s", c.MinVersion)
+	case c.Deadline != nil && now.After(*c.Deadline):
+		return fmt.Sprintf("团队要求在 %s 前完成更新", formatTime(*c.Deadline))
+	}
+	return ""
+}
+
+// chromePlusHold 最新 Chrome++ 版本未经批准时返回原因，否则返回空字符串
+func (t *TeamPolicy) chromePlusHold(latest string) string {
+	if t == nil || t.ChromePlusTag == "" || sameTag(t.ChromePlusTag, latest) {
+		return ""
+	}
+	return fmt.Sprintf("团队批准的版本为 %s", t.ChromePlusTag)
+}
+
+// chromePlusUrgency 团队策略要求 Chrome++ 必须更新时返回原因，否则返回空字符串
+func (t *TeamPolicy) chromePlusUrgency(now time.Time) string {
+	if t == nil || t.ChromePlusDeadline == nil || !now.After(*t.ChromePlusDeadline) {
+		return ""
+	}
+	return fmt.Sprintf("团队要求在 %s 前完成更新", formatTime(*t.ChromePlusDeadline))
+}
+
+// sameTag 比较版本 tag，忽略 v 前缀
+func sameTag(a, b string) bool {
+	return strings.TrimPrefix(strings.TrimSpace(a), "v") == strings.TrimPrefix(strings.TrimSpace(b), "v")
+}
+
+// loadTeamPolicy 获取团队策略并应用强制覆盖的配置项
+// 未配置团队策略时返回 nil
+func (s *session) loadTeamPolicy(client *MetadataClient) (*TeamPolicy, error) {
+	if s.cfg.PolicyURL == "" {
+		return nil, nil
+	}
+	policy, err := client.FetchTeamPolicy(s.cfg.PolicyURL)
+	if err != nil {
+		return nil, fmt.Errorf("获取团队策略失败: %w", err)
+	}
+
+	keys, err := s.cfg.ApplyEnforced(policy.Enforce)
+	if err != nil {
+		return nil, fmt.Errorf("团队策略中的 enforce 无效: %w", err)
+	}
+	if len(keys) > 0 {
+		if err := ValidateMaintenanceWindows(s.cfg.MaintenanceWindows); err != nil {
+			return nil, fmt.Errorf("团队策略中的 enforce 无效: %w", err)
+		}
+		s.say("团队策略强制的配置项: %s", strings.Join(keys, ", "))
+	}
+	client.ChromePlusTag = policy.ChromePlusTag
+	return policy, nil
+}
@@ -0,0 +1,121 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeTestPolicy 在程序目录写入团队策略，返回策略文件路径
+func writeTestPolicy(t *testing.T, dir string, policy map[string]interface{}) string {
+	t.Helper()
+	path := filepath.Join(dir, "team", teamPolicyFile)
+	writeTestJSON(t, path, policy)
+	return path
+}
+
+func TestTeamPolicyEnforceSilent(t *testing.T) {
+	dir := setupTestInstall(t, nil)
+	cfg := loadTestConfig(t)
+	cfg.PolicyURL = writeTestPolicy(t, dir, map[string]interface{}{"enforce": map[string]interface{}{"silent": true}})
+	if err := cfg.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	ui := &ScriptedUI{}
+	if code := RunWithUI(nil, ui); code != ExitUpdated {
+		t.Fatalf("退出码 = %d, want %d, 调用: %+v", code, ExitUpdated, ui.Calls)
+	}
+	if len(ui.Calls) != 0 {
+		t.Fatalf("强制静默模式时不应调用界面: %+v", ui.Calls)
+	}
+	if _, err := os.Stat(cfg.GetLogFile()); err != nil {
+		t.Fatalf("强制静默模式时应写入日志: %v", err)
+	}
+	if got := loadTestConfig(t); got.Version != testLatestChrome || got.Silent {
+		t.Fatalf("Version = %s, Silent = %v（强制的配置项不应写回本地配置）", got.Version, got.Silent)
+	}
+}
+
+func TestTeamPolicyEnforceCheckInterval(t *testing.T) {
+	tests := []struct {
+		name       string
+		enforce    map[string]interface{}
+		wantPrompt bool // 第二次运行时是否发现上游的新版本
+	}{
+		{name: "未强制时每次都检查", wantPrompt: true},
+		{name: "强制检查间隔时使用缓存", enforce: map[string]interface{}{"check_interval_minutes": 1440}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := setupTestInstall(t, func(cfg *Config) {
+				cfg.Version = testLatestChrome
+				cfg.ChromePlusVersion = testLatestChromePlus
+				cfg.CheckIntervalMinutes = 0
+			})
+			cfg := loadTestConfig(t)
+			cfg.PolicyURL = writeTestPolicy(t, dir, map[string]interface{}{"enforce": tt.enforce})
+			if err := cfg.Save(); err != nil {
+				t.Fatal(err)
+			}
+
+			if code := RunWithUI(nil, &ScriptedUI{}); code != ExitUpToDate {
+				t.Fatalf("首次运行退出码 = %d", code)
+			}
+
+			// 上游发布新版本
+			dataPath := filepath.Join(dir, "source", chromeDataFile)
+			writeTestJSON(t, dataPath, ChromeData{getChromeDataKey("stable"): {Version: "122.0.1.0", URLs: []string{"chrome.zip"}}})
+			modTime := time.Now().Add(time.Hour)
+			if err := os.Chtimes(dataPath, modTime, modTime); err != nil {
+				t.Fatal(err)
+			}
+
+			ui := &ScriptedUI{Choices: []int{1}}
+			RunWithUI(nil, ui)
+			if prompted := len(ui.CallsTo("ShowChoice")) > 0; prompted != tt.wantPrompt {
+				t.Fatalf("提示更新 = %v, want %v", prompted, tt.wantPrompt)
+			}
+		})
+	}
+}
+
+func TestTeamPolicyChromeHold(t *testing.T) {
+	tests := []struct {
+		name       string
+		channel    ChannelPolicy
+		latest     string
+		wantReason string // 为空表示应当更新
+	}{
+		{name: "批准的版本即最新版本", channel: ChannelPolicy{Version: "121.0.1.0"}, latest: "121.0.1.0"},
+		{name: "批准的版本低于最新版本", channel: ChannelPolicy{Version: "120.5.0.0"}, latest: "121.0.1.0", wantReason: "数据源中没有该版本"},
+		{name: "数据源尚未提供批准的版本", channel: ChannelPolicy{Version: "122.0.1.0"}, latest: "121.0.1.0", wantReason: "数据源中没有该版本"},
+		{name: "超过最高版本", channel: ChannelPolicy{MaxVersion: "120.9"}, latest: "121.0.1.0", wantReason: "超过团队允许的最高版本"},
+		{name: "等于最高版本", channel: ChannelPolicy{MaxVersion: "121.0.1.0"}, latest: "121.0.1.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Version = "120.0.1.0"
+			cfg.ChromePlusVersion = "v1.0.0"
+			state := InstallState{ChromeExists: true, ChromePlusExists: true, VersionDirs: []string{"120.0.1.0"}}
+			latest := &VersionInfo{ChromeVersion: tt.latest, ChromePlusVersion: "v1.0.0"}
+			team := &TeamPolicy{Channels: map[string]ChannelPolicy{"stable": tt.channel}}
+
+			plan := BuildUpdatePlan(cfg, state, latest, PlanOptions{Now: time.Now(), Team: team})
+			if tt.wantReason == "" {
+				if plan.Change(ComponentChrome) == nil {
+					t.Fatalf("应当更新: Held = %+v", plan.Held)
+				}
+				return
+			}
+			if len(plan.Held) != 1 || !strings.Contains(plan.Held[0].Reason, tt.wantReason) {
+				t.Fatalf("Held = %+v, want reason %q", plan.Held, tt.wantReason)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// UI 用户交互界面
+// Windows 下使用 MessageBox（MessageBoxUI），终端下使用 TTYUI，测试中使用 ScriptedUI
+type UI interface {
+	ShowConfirm(title, message string) bool                 // 显示确认提示，返回用户是否确认
+	ShowChoice(title, message string, choices []string) int // 显示选项（最多 3 个），返回所选下标，取消时返回 -1
+	ShowInfo(title, message string)                         // 显示提示信息
+	ShowError(message string)                               // 显示错误信息
+	ShowConsole()                                           // 显示控制台，用于输出下载/解压进度
+}
+
+// TTYUI 基于终端输入输出的界面
+type TTYUI struct {
+	in  *bufio.Reader
+	out io.Writer // 为 nil 时使用当前的 os.Stdout
+}
+
+// NewTTYUI 创建终端界面，out 为 nil 时输出到 os.Stdout
+func NewTTYUI(in io.Reader, out io.Writer) *TTYUI {
+	return &TTYUI{in: bufio.NewReader(in), out: out}
+}
+
+// writer 获取输出目标
+func (t *TTYUI) writer() io.Writer {
+	if t.out == nil {
+		return os.Stdout
+	}
+	return t.out
+}
+
+// ShowConfirm 输出提示并读取 y/n 应答，无法读取时视为否
+func (t *TTYUI) ShowConfirm(title, message string) bool {
+	w := t.writer()
+	fmt.Fprintf(w, "== %s ==\n%s\n[y/N]: ", title, message)
+
+	line, err := t.in.ReadString('\n')
+	if err != nil && line == "" {
+		fmt.Fprintln(w)
+		return false
+	}
+	switch strings.ToLower(strings.TrimSpace(line)) {
+	case "y", "yes", "是":
+		return true
+	default:
+		return false
+	}
+}
+
+// ShowChoice 输出编号选项并读取所选编号，无法读取或输入无效时返回 -1
+func (t *TTYUI) ShowChoice(title, message string, choices []string) int {
+	w := t.writer()
+	fmt.Fprintf(w, "== %s ==\n%s\n", title, message)
+	for i, c := range choices {
+		fmt.Fprintf(w, "  %d) %s\n", i+1, c)
+	}
+	fmt.Fprint(w, "请选择: ")
+
+	line, err := t.in.ReadString('\n')
+	if err != nil && line == "" {
+		fmt.Fprintln(w)
+		return -1
+	}
+	var n int
+	if _, err := fmt.Sscanf(strings.TrimSpace(line), "%d", &n); err != nil || n < 1 || n > len(choices) {
+		return -1
+	}
+	return n - 1
+}
+
+// ShowInfo 输出提示信息
+func (t *TTYUI) ShowInfo(title, message string) {
+	fmt.Fprintf(t.writer(), "== %s ==\n%s\n", title, message)
+}
+
+// ShowError 输出错误信息
+func (t *TTYUI) ShowError(message string) {
+	fmt.Fprintf(t.writer(), "错误: %s\n", message)
+}
+
+// ShowConsole 终端本身即控制台，无需处理
+func (t *TTYUI) ShowConsole() {}
+
+// UICall 界面调用记录
+type UICall struct {
+	Method  string // ShowConfirm/ShowChoice/ShowInfo/ShowError/ShowConsole
+	Title   string
+	Message string
+}
+
+// ScriptedUI 按预设应答运行并记录所有调用的界面，用于测试
+type ScriptedUI struct {
+	Answers []bool   // ShowConfirm 依次返回的应答，用尽后返回 false
+	Choices []int    // ShowChoice 依次返回的下标，用尽后返回 -1
+	Calls   []UICall // 已发生的调用
+}
+
+// ShowConfirm 记录调用并返回下一个预设应答
+func (s *ScriptedUI) ShowConfirm(title, message string) bool {
+	s.Calls = append(s.Calls, UICall{Method: "ShowConfirm", Title: title, Message: message})
+	if len(s.Answers) == 0 {
+		return false
+	}
+	answer := s.Answers[0]
+	s.Answers = s.Answers[1:]
+	return answer
+}
+
+// ShowChoice 记录调用并返回下一个预设下标
+func (s *ScriptedUI) ShowChoice(title, message string, choices []string) int {
+	s.Calls = append(s.Calls, UICall{Method: "ShowChoice", Title: title, Message: message})
+	if len(s.Choices) == 0 {
+		return -1
+	}
+	choice := s.Choices[0]
+	s.Choices = s.Choices[1:]
+	return choice
+}
+
+// ShowInfo 记录调用
+func (s *ScriptedUI) ShowInfo(title, message string) {
+	s.Calls = append(s.Calls, UICall{Method: "ShowInfo", Title: title, Message: message})
+}
+
+// ShowError 记录调用
+func (s *ScriptedUI) ShowError(message string) {
+	s.Calls = append(s.Calls, UICall{Method: "ShowError", Message: message})
+}
+
+// ShowConsole 记录调用
+func (s *ScriptedUI) ShowConsole() {
+	s.Calls = append(s.Calls, UICall{Method: "ShowConsole"})
+}
+
+// CallsTo 返回指定方法的调用记录
+func (s *ScriptedUI) CallsTo(method string) []UICall {
+	var calls []UICall
+	for _, c := range s.Calls {
+		if c.Method == method {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
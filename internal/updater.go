@@ -4,19 +4,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
+	"time"
 )
 
 const (
 	// Chrome 官方数据源 (包含直接下载链接)
 	chromeDataURL = "https://raw.githubusercontent.com/Bush2021/chrome_installer/main/data.json"
-	// Chrome++ GitHub API
-	chromePlusAPI = "https://api.github.com/repos/Bush2021/chrome_plus/releases/latest"
 )
 
 // ChromeData data.json 结构
@@ -40,21 +39,43 @@ type GitHubRelease struct {
 // Asset Release 资源
 type Asset struct {
 	Name               string `json:"name"`
+	Size               int64  `json:"size"`
 	BrowserDownloadURL string `json:"browser_download_url"`
+	Digest             string `json:"digest,omitempty"` // 资源摘要，如 "sha256:<十六进制>"，GitHub 旧 Release 没有该字段
+}
+
+// SHA256 返回资源摘要中的 SHA256，没有时返回空字符串
+func (a *Asset) SHA256() string {
+	if hex, ok := strings.CutPrefix(a.Digest, "sha256:"); ok {
+		return strings.ToLower(hex)
+	}
+	return ""
 }
 
 // VersionInfo 版本信息
 type VersionInfo struct {
 	ChromeVersion     string   // Chrome 版本
 	ChromeURLs        []string // Chrome 安装包下载地址列表（多源）
+	ChromeSize        int64    // Chrome 安装包大小
+	ChromeSHA256      string   // Chrome 安装包 SHA256
 	ChromePlusVersion string   // Chrome++ 版本
 	ChromePlusURL     string   // Chrome++ 下载地址
+	ChromePlusURLs    []string // Chrome++ 下载地址列表（加速镜像在前，原地址在最后）
+	ChromePlusSize    int64    // Chrome++ 压缩包大小
+	ChromePlusSHA256  string   // Chrome++ 压缩包 SHA256（来自资源摘要），未知时为空
+	ChromePlusAsset   string   // Chrome++ 压缩包文件名
+	ChromePlusError   string   // Chrome++ 信息获取失败的原因，此时 ChromePlusVersion 为空
+	ChromePlusPinned  bool     // Chrome++ 为指定的版本（团队策略批准的版本）而不是最新版本
+
+	MetadataTime   time.Time // Chrome 元数据时间（签名时间或 Last-Modified），零值表示未知
+	MetadataSigned bool      // MetadataTime 来自受信任的签名
 }
 
 // GetLatestVersion 获取最新版本信息
-func GetLatestVersion(channel string) (*VersionInfo, error) {
+func (m *MetadataClient) GetLatestVersion(channel string) (*VersionInfo, error) {
 	// 从 data.json 获取 Chrome 信息
-	chromeData, err := fetchChromeData()
+	dataURL := m.chromeDataSource()
+	chromeData, err := m.fetchChromeData(dataURL)
 	if err != nil {
 		return nil, fmt.Errorf("获取 Chrome 版本失败: %w", err)
 	}
@@ -66,30 +87,77 @@ func GetLatestVersion(channel string) (*VersionInfo, error) {
 		return nil, fmt.Errorf("未找到通道 %s 的数据", channel)
 	}
 
-	// 排序 URL：优先 dl.google.com 和 www.google.com
-	chromeURLs := sortURLsByPriority(channelData.URLs)
+	info := &VersionInfo{
+		ChromeVersion: channelData.Version,
+		ChromeURLs:    m.chromeURLs(dataURL, channelData.URLs),
+		ChromeSize:    channelData.Size,
+		ChromeSHA256:  channelData.SHA256,
+	}
+	info.MetadataTime, info.MetadataSigned = m.metadataTime(dataURL, m.RequireSignature && m.ChromeDataURL != "")
 
-	// 获取 Chrome++ 信息
-	plusRelease, err := fetchRelease(chromePlusAPI)
+	// 获取 Chrome++ 信息，失败时不影响 Chrome 更新
+	plusRelease, plusBase, err := m.fetchChromePlusRelease()
 	if err != nil {
-		return nil, fmt.Errorf("获取 Chrome++ 版本失败: %w", err)
+		info.ChromePlusError = fmt.Sprintf("获取 Chrome++ 版本失败: %v", err)
+		return info, nil
 	}
 
 	// 查找 chrome_plus 压缩包
-	plusURL := ""
-	for _, asset := range plusRelease.Assets {
-		if strings.HasSuffix(asset.Name, ".7z") {
-			plusURL = asset.BrowserDownloadURL
-			break
+	asset := chromePlusAsset(plusRelease)
+	if asset == nil {
+		info.ChromePlusError = fmt.Sprintf("Chrome++ %s 中没有找到支持的压缩包", plusRelease.TagName)
+		return info, nil
+	}
+	info.ChromePlusVersion = plusRelease.TagName
+	info.ChromePlusPinned = m.ChromePlusTag != "" && m.ChromePlusReleaseURL == ""
+	info.ChromePlusURL = ResolveSource(plusBase, asset.BrowserDownloadURL)
+	info.ChromePlusURLs = GitHubMirrorURLs(m.GitHubMirrors, info.ChromePlusURL)
+	info.ChromePlusSize = asset.Size
+	info.ChromePlusSHA256 = asset.SHA256()
+	info.ChromePlusAsset = asset.Name
+	return info, nil
+}
+
+// chromePlusAsset 查找 Release 中的 Chrome++ 压缩包，有多个时按 archiveExtensions 的顺序选择
+func chromePlusAsset(release *GitHubRelease) *Asset {
+	for _, ext := range archiveExtensions {
+		for i, asset := range release.Assets {
+			if archiveExt(asset.Name) == ext {
+				return &release.Assets[i]
+			}
+		}
+	}
+	return nil
+}
+
+// chromePlusExt 返回 Chrome++ 压缩包的扩展名，未知时为 .7z
+func chromePlusExt(assetName string) string {
+	if ext := archiveExt(assetName); ext != "" {
+		return ext
+	}
+	return ".7z"
+}
+
+// chromeURLs 处理 data.json 中的下载地址，返回按优先级排列的候选地址
+// 本地路径和相对地址基于 data.json 所在位置解析；与 data.json 同源的地址（如局域网缓存服务）优先且允许 http，
+// 其后是改写规则生成的镜像地址和按优先级排序的原地址
+func (m *MetadataClient) chromeURLs(dataURL string, urls []string) []string {
+	var origin, sources []string
+	for _, u := range urls {
+		resolved := ResolveSource(dataURL, u)
+		if !isFileURL(resolved) && sameHost(dataURL, resolved) {
+			origin = append(origin, resolved)
+		} else {
+			sources = append(sources, resolved)
 		}
 	}
 
-	return &VersionInfo{
-		ChromeVersion:     channelData.Version,
-		ChromeURLs:        chromeURLs,
-		ChromePlusVersion: plusRelease.TagName,
-		ChromePlusURL:     plusURL,
-	}, nil
+	// 按改写规则生成内网镜像地址，作为最高优先级
+	rewritten, originals := rewriteURLs(m.URLRewrites, sources)
+
+	// 排序 URL：优先 dl.google.com 和 www.google.com
+	result := append(origin, rewritten...)
+	return append(result, sortURLsByPriority(originals)...)
 }
 
 // getChromeDataKey 根据通道返回 data.json 中的 key
@@ -106,34 +174,40 @@ func getChromeDataKey(channel string) string {
 	}
 }
 
-// fetchChromeData 获取 Chrome 数据
-func fetchChromeData() (ChromeData, error) {
-	resp, err := http.Get(chromeDataURL)
-	if err != nil {
-		return nil, err
+// chromeDataSource 返回 Chrome 版本数据地址
+func (m *MetadataClient) chromeDataSource() string {
+	if m.ChromeDataURL == "" {
+		return chromeDataURL
 	}
-	defer resp.Body.Close()
+	return metadataSource(m.ChromeDataURL, chromeDataFile)
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+// fetchChromeData 获取 Chrome 数据，自定义数据源要求签名时校验签名
+func (m *MetadataClient) fetchChromeData(url string) (ChromeData, error) {
+	body, err := m.fetchMetadata(url, nil, m.RequireSignature && m.ChromeDataURL != "")
+	if err != nil {
+		return nil, err
 	}
-
 	var data ChromeData
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+	if err := json.Unmarshal(body, &data); err != nil {
 		return nil, err
 	}
-
 	return data, nil
 }
 
 // sortURLsByPriority 按优先级排序 URL
-// 优先级: dl.google.com > www.google.com > 其他
+// 优先级: 本地文件 > dl.google.com > www.google.com > 其他
 func sortURLsByPriority(urls []string) []string {
+	var local []string     // file://
 	var priority1 []string // dl.google.com
 	var priority2 []string // www.google.com
 	var priority3 []string // 其他
 
 	for _, url := range urls {
+		if isFileURL(url) {
+			local = append(local, url)
+			continue
+		}
 		if !strings.HasPrefix(url, "https://") {
 			continue // 只使用 HTTPS
 		}
@@ -147,102 +221,165 @@ func sortURLsByPriority(urls []string) []string {
 	}
 
 	result := make([]string, 0, len(urls))
+	result = append(result, local...)
 	result = append(result, priority1...)
 	result = append(result, priority2...)
 	result = append(result, priority3...)
 	return result
 }
 
-// fetchRelease 调用 GitHub API 获取 Release 信息
-func fetchRelease(url string) (*GitHubRelease, error) {
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-	req.Header.Set("Accept", "application/vnd.github.v3+json")
-	req.Header.Set("User-Agent", "ChromeGo-Updater")
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+// progressPrinter 返回输出下载进度的回调
+// plain 为 true 时每 10% 输出一行带时间戳的日志，适合写入日志文件
+func progressPrinter(name string, plain bool) DownloadProgress {
+	if !plain {
+		return func(downloaded, total int64) {
+			if total > 0 {
+				percent := float64(downloaded) / float64(total) * 100
+				fmt.Printf("\r正在下载 %s: %s / %s (%.1f%%)    ", name, FormatBytes(downloaded), FormatBytes(total), percent)
+			}
+		}
 	}
 
-	var release GitHubRelease
-	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
-		return nil, err
+	var mu sync.Mutex
+	lastStep := int64(-1)
+	return func(downloaded, total int64) {
+		if total <= 0 {
+			return
+		}
+		step := downloaded * 10 / total
+		mu.Lock()
+		defer mu.Unlock()
+		if step <= lastStep {
+			return
+		}
+		lastStep = step
+		logf("正在下载 %s: %s / %s (%d%%)", name, FormatBytes(downloaded), FormatBytes(total), step*10)
 	}
-
-	return &release, nil
 }
 
-// DownloadChromeWithProgress 多源多线程下载 Chrome 并显示进度
-func DownloadChromeWithProgress(urls []string, destPath string, threads int) error {
-	return MultiSourceDownload(urls, destPath, threads, func(downloaded, total int64) {
-		if total > 0 {
-			percent := float64(downloaded) / float64(total) * 100
-			fmt.Printf("\r正在下载 Chrome: %s / %s (%.1f%%)    ", FormatBytes(downloaded), FormatBytes(total), percent)
+// extractPrinter 返回输出解压进度的回调，每 10% 输出一行（嵌套压缩包的每一层分别计算）
+func extractPrinter(name string) ExtractProgress {
+	lastStep, lastTotal := int64(-1), int64(0)
+	return func(done, total int64) {
+		if total <= 0 {
+			return
 		}
-	})
+		if total != lastTotal {
+			lastStep, lastTotal = -1, total
+		}
+		step := done * 10 / total
+		if step <= lastStep || step > 10 {
+			return
+		}
+		lastStep = step
+		fmt.Printf("正在解压 %s: %d%%\n", name, step*10)
+	}
 }
 
-// DownloadFileWithProgress 下载文件并显示进度（单源）
-func DownloadFileWithProgress(url, destPath, name string, threads int) error {
-	return MultiThreadDownload(url, destPath, threads, func(downloaded, total int64) {
-		if total > 0 {
-			percent := float64(downloaded) / float64(total) * 100
-			fmt.Printf("\r正在下载 %s: %s / %s (%.1f%%)    ", name, FormatBytes(downloaded), FormatBytes(total), percent)
-		}
-	})
+// archiveRoot 返回解压目录中的内容根目录：只有一个子目录且没有文件时为该子目录
+func archiveRoot(dir string) string {
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 || !entries[0].IsDir() {
+		return dir
+	}
+	return filepath.Join(dir, entries[0].Name())
 }
 
 // ExtractChrome 解压 Chrome 安装包
 // 安装包结构: Chrome-bin\chrome.exe -> 需要移动到 App\chrome.exe
 func ExtractChrome(archivePath, destDir string) error {
 	tempDir := destDir + "_temp"
+	defer os.RemoveAll(tempDir) // 确保清理临时目录
 
-	// 使用内置 7z 解压
-	if err := Extract7z(archivePath, tempDir); err != nil {
-		os.RemoveAll(tempDir)
+	binDir, _, err := unpackChrome(archivePath, tempDir)
+	if err != nil {
 		return err
 	}
+	return installChromeFiles(binDir, destDir)
+}
+
+// unpackChrome 将 Chrome 安装包解压到临时目录，返回 Chrome 文件所在目录和版本（未找到版本目录时为空）
+// 只写入临时目录，调用方确认版本后再用 installChromeFiles 复制到安装目录
+func unpackChrome(archivePath, tempDir string) (string, string, error) {
+	// 离线安装程序优先直接从 PE 资源中解压，失败时按压缩包处理
+	progress := extractPrinter("Chrome")
+	extracted := false
+	if isPEFile(archivePath) {
+		if err := ExtractInstallerPayload(archivePath, tempDir, progress); err != nil {
+			fmt.Printf("从安装程序资源解压失败: %v，尝试按压缩包解压...\n", err)
+			os.RemoveAll(tempDir)
+		} else {
+			extracted = true
+		}
+	}
+
+	// 按文件内容识别压缩格式解压
+	if !extracted {
+		if err := ExtractArchive(archivePath, tempDir, progress); err != nil {
+			return "", "", err
+		}
+	}
 
-	// 移动 Chrome-bin 内容到目标目录
+	// Chrome-bin 中为 Chrome 文件
 	chromeBinDir := filepath.Join(tempDir, "Chrome-bin")
 	if _, err := os.Stat(chromeBinDir); os.IsNotExist(err) {
-		// 如果没有 Chrome-bin 目录，可能直接就是内容
-		chromeBinDir = tempDir
+		// 如果没有 Chrome-bin 目录，可能直接就是内容（zip 等压缩包通常还有一层顶层目录）
+		chromeBinDir = archiveRoot(tempDir)
 	}
 
-	// 确保目标目录存在
+	// 版本目录名即为 Chrome 版本
+	version := ""
+	if entries, err := os.ReadDir(chromeBinDir); err == nil {
+		for _, entry := range entries {
+			if entry.IsDir() && versionDirRegex.MatchString(entry.Name()) {
+				version = entry.Name()
+			}
+		}
+	}
+	return chromeBinDir, version, nil
+}
+
+// installChromeFiles 将解压出的 Chrome 文件复制到安装目录
+func installChromeFiles(binDir, destDir string) error {
 	if err := os.MkdirAll(destDir, 0755); err != nil {
-		os.RemoveAll(tempDir)
 		return err
 	}
-
-	// 复制文件
-	err := copyDir(chromeBinDir, destDir)
-	os.RemoveAll(tempDir) // 确保清理临时目录
-	return err
+	return copyDir(binDir, destDir)
 }
 
 // ExtractChromePlus 解压 Chrome++ 增强包
 // 包结构: x64\App\version.dll, x64\App\chrome++.ini
 func ExtractChromePlus(archivePath, destDir string) error {
 	tempDir := destDir + "_plus_temp"
+	defer os.RemoveAll(tempDir) // 确保清理临时目录
 
-	// 使用内置 7z 解压
-	if err := Extract7z(archivePath, tempDir); err != nil {
-		os.RemoveAll(tempDir)
+	srcDir, err := unpackChromePlus(archivePath, tempDir)
+	if err != nil {
 		return err
 	}
+	return installChromePlusFiles(srcDir, destDir)
+}
+
+// unpackChromePlus 将 Chrome++ 增强包解压到临时目录，返回 version.dll 和 chrome++.ini 所在目录
+func unpackChromePlus(archivePath, tempDir string) (string, error) {
+	// 按文件内容识别压缩格式解压
+	if err := ExtractArchive(archivePath, tempDir, extractPrinter("Chrome++")); err != nil {
+		return "", err
+	}
 
-	// 源文件路径
+	// 源文件路径：x64 位于压缩包根目录，或位于唯一的顶层目录中
 	srcDir := filepath.Join(tempDir, "x64", "App")
+	if !fileExists(srcDir) {
+		srcDir = filepath.Join(archiveRoot(tempDir), "x64", "App")
+	}
+	if !fileExists(filepath.Join(srcDir, "version.dll")) {
+		return "", fmt.Errorf("增强包中未找到 x64/App/version.dll")
+	}
+	return srcDir, nil
+}
+
+// installChromePlusFiles 将解压出的 version.dll 复制到安装目录，chrome++.ini 不存在时复制，存在时合并
+func installChromePlusFiles(srcDir, destDir string) error {
 	versionDll := filepath.Join(srcDir, "version.dll")
 	chromePlusIni := filepath.Join(srcDir, "chrome++.ini")
 
@@ -252,7 +389,6 @@ func ExtractChromePlus(archivePath, destDir string) error {
 
 	// 复制 version.dll
 	if err := copyFile(versionDll, destVersionDll); err != nil {
-		os.RemoveAll(tempDir)
 		return fmt.Errorf("复制 version.dll 失败: %w", err)
 	}
 
@@ -260,18 +396,14 @@ func ExtractChromePlus(archivePath, destDir string) error {
 	if _, err := os.Stat(destIni); os.IsNotExist(err) {
 		// 本地不存在，直接复制并设置默认路径
 		if err := copyFile(chromePlusIni, destIni); err != nil {
-			os.RemoveAll(tempDir)
 			return fmt.Errorf("复制 chrome++.ini 失败: %w", err)
 		}
 		// 设置默认的数据目录和缓存目录
-		os.RemoveAll(tempDir)
 		return updateIniPaths(destIni)
 	}
 
 	// 本地存在，执行合并
-	err := MergeIni(chromePlusIni, destIni)
-	os.RemoveAll(tempDir) // 确保清理临时目录
-	return err
+	return MergeIni(chromePlusIni, destIni)
 }
 
 // updateIniPaths 更新 INI 文件中的路径配置
@@ -0,0 +1,223 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Webhook 事件
+const (
+	EventUpdateAvailable = "update_available" // 发现新版本
+	EventUpdateApplied   = "update_applied"   // 更新完成
+	EventUpdateFailed    = "update_failed"    // 更新失败
+	EventRolledBack      = "rolled_back"      // 更新失败，已恢复到更新前的版本
+)
+
+// Webhook 消息格式
+const (
+	WebhookJSON     = "json"     // 通用 JSON，可用 template 自定义请求体
+	WebhookSlack    = "slack"    // Slack Incoming Webhook
+	WebhookDiscord  = "discord"  // Discord Webhook
+	WebhookTeams    = "teams"    // Microsoft Teams Incoming Webhook
+	WebhookFeishu   = "feishu"   // 飞书自定义机器人
+	WebhookDingTalk = "dingtalk" // 钉钉自定义机器人
+	WebhookWeCom    = "wecom"    // 企业微信群机器人
+)
+
+// WebhookTarget Webhook 通知目标
+type WebhookTarget struct {
+	URL      string   `json:"url"`
+	Format   string   `json:"format"`   // 消息格式: json（默认）/slack/discord/teams/feishu/dingtalk/wecom
+	Events   []string `json:"events"`   // 通知的事件，为空表示全部
+	Template string   `json:"template"` // json 格式的请求体模板（Go text/template），为空时发送事件的 JSON
+}
+
+// WebhookEvent Webhook 事件内容，也是请求体模板的数据
+type WebhookEvent struct {
+	Event     string    `json:"event"`
+	Hostname  string    `json:"hostname"`
+	Component string    `json:"component"` // chrome / chrome_plus
+	Name      string    `json:"name"`      // 组件显示名称
+	From      string    `json:"from"`
+	To        string    `json:"to"`
+	Error     string    `json:"error,omitempty"`
+	Message   string    `json:"message"` // 可读的通知文字
+	Time      time.Time `json:"time"`
+}
+
+// newWebhookEvent 根据组件变更生成事件
+func newWebhookEvent(event string, change ComponentChange, errMessage string, now time.Time) WebhookEvent {
+	hostname, _ := os.Hostname()
+	e := WebhookEvent{
+		Event:     event,
+		Hostname:  hostname,
+		Component: change.Component,
+		Name:      componentName(change.Component),
+		From:      change.From,
+		To:        change.To,
+		Error:     errMessage,
+		Time:      now,
+	}
+	if change.Component == "" {
+		// 离线安装时无法识别的安装包
+		e.Name = "安装包"
+	}
+
+	from := e.From
+	if from == "" {
+		from = "未安装"
+	}
+	switch event {
+	case EventUpdateAvailable:
+		e.Message = fmt.Sprintf("[%s] 发现 %s 新版本: %s → %s", hostname, e.Name, from, e.To)
+	case EventUpdateApplied:
+		e.Message = fmt.Sprintf("[%s] %s 已更新: %s → %s", hostname, e.Name, from, e.To)
+	case EventUpdateFailed:
+		e.Message = fmt.Sprintf("[%s] %s 更新失败（%s → %s）: %s", hostname, e.Name, from, e.To, errMessage)
+	case EventRolledBack:
+		e.Message = fmt.Sprintf("[%s] %s 更新失败，已恢复到 %s: %s", hostname, e.Name, from, errMessage)
+	}
+	return e
+}
+
+// wants 目标是否订阅该事件
+func (w WebhookTarget) wants(event string) bool {
+	return len(w.Events) == 0 || containsString(w.Events, event)
+}
+
+// payload 按消息格式生成请求体
+func (w WebhookTarget) payload(e WebhookEvent) ([]byte, error) {
+	text := e.Message
+	switch strings.ToLower(w.Format) {
+	case "", WebhookJSON:
+		if w.Template == "" {
+			return json.Marshal(e)
+		}
+		tmpl, err := template.New("webhook").Funcs(template.FuncMap{"json": jsonString}).Parse(w.Template)
+		if err != nil {
+			return nil, fmt.Errorf("模板无效: %w", err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, e); err != nil {
+			return nil, fmt.Errorf("模板执行失败: %w", err)
+		}
+		return buf.Bytes(), nil
+	case WebhookSlack, WebhookTeams:
+		return json.Marshal(map[string]string{"text": text})
+	case WebhookDiscord:
+		return json.Marshal(map[string]string{"content": text})
+	case WebhookFeishu:
+		return json.Marshal(map[string]interface{}{"msg_type": "text", "content": map[string]string{"text": text}})
+	case WebhookDingTalk, WebhookWeCom:
+		return json.Marshal(map[string]interface{}{"msgtype": "text", "text": map[string]string{"content": text}})
+	default:
+		return nil, fmt.Errorf("未知的消息格式: %s", w.Format)
+	}
+}
+
+// jsonString 模板函数：将值编码为 JSON，用于在模板中安全地嵌入字符串
+func jsonString(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+// send 发送通知
+func (w WebhookTarget) send(e WebhookEvent) error {
+	body, err := w.payload(e)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ChromeGo/"+Version)
+
+	client := &http.Client{Transport: httpClient.Transport, Timeout: 10 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &HTTPError{StatusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+// SendWebhooks 向订阅了该事件的目标发送通知，返回各目标的错误
+func SendWebhooks(targets []WebhookTarget, e WebhookEvent) []error {
+	var errs []error
+	for _, w := range targets {
+		if !w.wants(e.Event) {
+			continue
+		}
+		if err := w.send(e); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", w.URL, err))
+		}
+	}
+	return errs
+}
+
+// notify 发送组件事件通知，失败时只记录警告
+func (s *session) notify(event string, change ComponentChange, errMessage string) {
+	if len(s.cfg.Webhooks) == 0 {
+		return
+	}
+	for _, err := range SendWebhooks(s.cfg.Webhooks, newWebhookEvent(event, change, errMessage, time.Now())) {
+		s.warn("发送通知失败: " + err.Error())
+	}
+}
+
+// notifyAvailable 为计划中首次发现的新版本发送通知（包括暂不更新的版本），返回配置是否被修改
+// 已通知的版本记录在配置中，同一版本只通知一次
+func (s *session) notifyAvailable(plan *UpdatePlan) bool {
+	if len(s.cfg.Webhooks) == 0 {
+		return false
+	}
+	changed := false
+	for _, c := range append(append([]ComponentChange{}, plan.Changes...), plan.Held...) {
+		if c.Install || s.cfg.NotifiedVersions[c.Component] == c.To {
+			continue
+		}
+		s.notify(EventUpdateAvailable, c, "")
+		if s.cfg.NotifiedVersions == nil {
+			s.cfg.NotifiedVersions = make(map[string]string)
+		}
+		s.cfg.NotifiedVersions[c.Component] = c.To
+		changed = true
+	}
+	return changed
+}
+
+// failUpdate 更新失败退出：发送 update_failed（已恢复时同时发送 rolled_back），以 prefix + 错误原因结束
+// 预演模式不发送通知
+func (s *session) failUpdate(changes []ComponentChange, prefix string, err error) int {
+	if !s.opts.DryRun {
+		s.notifyResult(changes, err)
+	}
+	return s.fail(prefix + err.Error())
+}
+
+// notifyResult 根据更新结果发送通知：成功为 update_applied，失败为 update_failed，已恢复时同时发送 rolled_back
+func (s *session) notifyResult(changes []ComponentChange, err error) {
+	for _, c := range changes {
+		if err == nil {
+			s.notify(EventUpdateApplied, c, "")
+			continue
+		}
+		s.notify(EventUpdateFailed, c, err.Error())
+		var rolledBack *RolledBackError
+		if errors.As(err, &rolledBack) {
+			s.notify(EventRolledBack, c, rolledBack.Err.Error())
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package internal
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newWebhookServer 返回记录收到的事件的 Webhook 替身服务
+func newWebhookServer(t *testing.T) (*httptest.Server, func() []WebhookEvent) {
+	t.Helper()
+	var mu sync.Mutex
+	var events []WebhookEvent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var e WebhookEvent
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		events = append(events, e)
+		mu.Unlock()
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []WebhookEvent {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]WebhookEvent{}, events...)
+	}
+}
+
+// outsideWindow 返回不包含当前时间的维护窗口
+func outsideWindow(now time.Time) MaintenanceWindow {
+	return MaintenanceWindow{Start: now.Add(2 * time.Hour).Format("15:04"), End: now.Add(3 * time.Hour).Format("15:04")}
+}
+
+func TestUpdateFailedWebhook(t *testing.T) {
+	tests := []struct {
+		name      string
+		configure func(cfg *Config)
+		args      func(dir string) []string
+		wantName  string // update_failed 事件的组件名称
+		wantError string
+	}{
+		{
+			name:      "下载的安装包校验失败",
+			args:      func(string) []string { return []string{"--silent"} },
+			wantName:  "Chrome",
+			wantError: "SHA256 校验失败",
+		},
+		{
+			name:      "维护窗口外暂存失败",
+			configure: func(cfg *Config) { cfg.MaintenanceWindows = []MaintenanceWindow{outsideWindow(time.Now())} },
+			args:      func(string) []string { return []string{"--silent"} },
+			wantName:  "Chrome",
+			wantError: "SHA256 校验失败",
+		},
+		{
+			name:      "离线安装包不存在",
+			args:      func(dir string) []string { return []string{"install", "--from", filepath.Join(dir, "missing.zip")} },
+			wantName:  "安装包",
+			wantError: "安装包不存在",
+		},
+		{
+			name: "离线安装包 SHA256 不符",
+			args: func(dir string) []string {
+				return []string{"install", "--from", filepath.Join(dir, "source", "chrome.zip"), "--sha256", strings.Repeat("0", 64)}
+			},
+			wantName:  "安装包",
+			wantError: "SHA256 校验失败",
+		},
+		{
+			name: "离线安装包无法识别",
+			args: func(dir string) []string {
+				return []string{"install", "--from", filepath.Join(dir, "source", chromeDataFile)}
+			},
+			wantName: "安装包",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, events := newWebhookServer(t)
+			dir := setupTestInstall(t, func(cfg *Config) {
+				cfg.Webhooks = []WebhookTarget{{URL: server.URL, Events: []string{EventUpdateFailed}}}
+				if tt.configure != nil {
+					tt.configure(cfg)
+				}
+			})
+			// 上游 SHA256 与安装包不符
+			dataPath := filepath.Join(dir, "source", chromeDataFile)
+			var data ChromeData
+			if err := json.Unmarshal([]byte(readTestFile(t, dataPath)), &data); err != nil {
+				t.Fatal(err)
+			}
+			entry := data[getChromeDataKey("stable")]
+			entry.SHA256 = strings.Repeat("0", 64)
+			data[getChromeDataKey("stable")] = entry
+			writeTestJSON(t, dataPath, data)
+
+			if code := RunWithUI(tt.args(dir), &ScriptedUI{}); code != ExitFailed {
+				t.Fatalf("退出码 = %d, want %d", code, ExitFailed)
+			}
+
+			var failed []WebhookEvent
+			for _, e := range events() {
+				if e.Event == EventUpdateFailed && e.Name == tt.wantName {
+					failed = append(failed, e)
+				}
+			}
+			if len(failed) != 1 || !strings.Contains(failed[0].Error, tt.wantError) {
+				t.Fatalf("update_failed 事件 = %+v, want %s: %q", events(), tt.wantName, tt.wantError)
+			}
+		})
+	}
+}
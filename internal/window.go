@@ -0,0 +1,125 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaintenanceWindow 维护窗口，静默和后台更新只在窗口内安装
+type MaintenanceWindow struct {
+	Days  []string `json:"days"`  // 星期，如 ["sat", "sun"] 或 ["周六", "周日"]，为空表示每天
+	Start string   `json:"start"` // 开始时间（本地时区），如 "22:00"
+	End   string   `json:"end"`   // 结束时间，早于开始时间表示跨越午夜，如 "06:00"
+}
+
+// weekdayNames 星期名称
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "sunday": time.Sunday, "周日": time.Sunday, "周天": time.Sunday,
+	"mon": time.Monday, "monday": time.Monday, "周一": time.Monday,
+	"tue": time.Tuesday, "tuesday": time.Tuesday, "周二": time.Tuesday,
+	"wed": time.Wednesday, "wednesday": time.Wednesday, "周三": time.Wednesday,
+	"thu": time.Thursday, "thursday": time.Thursday, "周四": time.Thursday,
+	"fri": time.Friday, "friday": time.Friday, "周五": time.Friday,
+	"sat": time.Saturday, "saturday": time.Saturday, "周六": time.Saturday,
+}
+
+// InMaintenanceWindow 判断当前时间是否处于任一维护窗口内
+// 未配置维护窗口时始终返回 true；窗口在加载配置时已校验，无效的窗口视为不在窗口内
+func InMaintenanceWindow(windows []MaintenanceWindow, now time.Time) bool {
+	if len(windows) == 0 {
+		return true
+	}
+	for _, w := range windows {
+		if ok, err := w.Contains(now); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateMaintenanceWindows 校验维护窗口，返回第一个无效窗口的错误
+func ValidateMaintenanceWindows(windows []MaintenanceWindow) error {
+	for i, w := range windows {
+		if err := w.Validate(); err != nil {
+			return fmt.Errorf("维护窗口 %d 无效: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// Validate 校验维护窗口的开始、结束时间和星期
+func (w MaintenanceWindow) Validate() error {
+	if _, err := parseClock(w.Start); err != nil {
+		return err
+	}
+	if _, err := parseClock(w.End); err != nil {
+		return err
+	}
+	_, err := w.weekdays()
+	return err
+}
+
+// Contains 判断时间是否处于维护窗口内（使用 now 所在时区）
+func (w MaintenanceWindow) Contains(now time.Time) (bool, error) {
+	start, err := parseClock(w.Start)
+	if err != nil {
+		return false, err
+	}
+	end, err := parseClock(w.End)
+	if err != nil {
+		return false, err
+	}
+	days, err := w.weekdays()
+	if err != nil {
+		return false, err
+	}
+
+	minute := now.Hour()*60 + now.Minute()
+	today := now.Weekday()
+	yesterday := (today + 6) % 7
+
+	if start == end {
+		// 开始等于结束表示全天
+		return days[today], nil
+	}
+	if start < end {
+		return days[today] && minute >= start && minute < end, nil
+	}
+	// 跨越午夜：当天开始后，或前一天开始的窗口尚未结束
+	return (days[today] && minute >= start) || (days[yesterday] && minute < end), nil
+}
+
+// weekdays 解析窗口生效的星期，为空表示每天
+func (w MaintenanceWindow) weekdays() (map[time.Weekday]bool, error) {
+	days := make(map[time.Weekday]bool)
+	if len(w.Days) == 0 {
+		for d := time.Sunday; d <= time.Saturday; d++ {
+			days[d] = true
+		}
+		return days, nil
+	}
+	for _, name := range w.Days {
+		d, ok := weekdayNames[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return nil, fmt.Errorf("无效的星期: %s", name)
+		}
+		days[d] = true
+	}
+	return days, nil
+}
+
+// parseClock 解析 "HH:MM" 格式的时间，返回当天的分钟数
+func parseClock(value string) (int, error) {
+	h, m, ok := strings.Cut(strings.TrimSpace(value), ":")
+	hour, hourErr := strconv.Atoi(h)
+	minute, minuteErr := strconv.Atoi(m)
+	if !ok || hourErr != nil || minuteErr != nil || len(m) != 2 {
+		return 0, fmt.Errorf("无效的时间: %q", value)
+	}
+	if hour < 0 || hour > 24 || minute < 0 || minute > 59 || (hour == 24 && minute != 0) {
+		return 0, fmt.Errorf("无效的时间: %q", value)
+	}
+	return hour*60 + minute, nil
+}
@@ -0,0 +1,112 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaintenanceWindowContains(t *testing.T) {
+	// 2024-05-03 是周五
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 5, day, hour, minute, 0, 0, time.UTC)
+	}
+	weekdays := []string{"mon", "tue", "wed", "thu", "fri"}
+
+	tests := []struct {
+		name   string
+		window MaintenanceWindow
+		now    time.Time
+		want   bool
+	}{
+		{"当天窗口内", MaintenanceWindow{Start: "09:00", End: "17:00"}, at(3, 12, 0), true},
+		{"当天窗口结束时刻", MaintenanceWindow{Start: "09:00", End: "17:00"}, at(3, 17, 0), false},
+		{"跨越午夜当天开始后", MaintenanceWindow{Days: weekdays, Start: "22:00", End: "06:00"}, at(3, 23, 30), true},
+		{"跨越午夜次日结束前", MaintenanceWindow{Days: weekdays, Start: "22:00", End: "06:00"}, at(4, 5, 59), true},
+		{"跨越午夜次日结束后", MaintenanceWindow{Days: weekdays, Start: "22:00", End: "06:00"}, at(4, 6, 0), false},
+		{"跨越午夜前一天不在星期列表", MaintenanceWindow{Days: weekdays, Start: "22:00", End: "06:00"}, at(5, 1, 0), false},
+		{"星期列表外", MaintenanceWindow{Days: []string{"sat", "sun"}, Start: "00:00", End: "00:00"}, at(3, 12, 0), false},
+		{"中文星期全天", MaintenanceWindow{Days: []string{"周六", "周日"}, Start: "00:00", End: "00:00"}, at(4, 12, 0), true},
+		{"结束时间 24:00", MaintenanceWindow{Start: "20:00", End: "24:00"}, at(3, 23, 59), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.window.Contains(tt.now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("Contains(%v) = %v, want %v", tt.now, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateMaintenanceWindows(t *testing.T) {
+	tests := []struct {
+		name    string
+		windows []MaintenanceWindow
+		wantErr string
+	}{
+		{name: "未配置"},
+		{name: "有效", windows: []MaintenanceWindow{{Days: []string{"Sat", " sunday "}, Start: "22:00", End: "6:00"}}},
+		{name: "小时超出范围", windows: []MaintenanceWindow{{Start: "25:00", End: "06:00"}}, wantErr: "维护窗口 1 无效"},
+		{name: "分钟超出范围", windows: []MaintenanceWindow{{Start: "22:60", End: "06:00"}}, wantErr: "无效的时间"},
+		{name: "缺少分钟", windows: []MaintenanceWindow{{Start: "22", End: "06:00"}}, wantErr: "无效的时间"},
+		{name: "多余字符", windows: []MaintenanceWindow{{Start: "22:00pm", End: "06:00"}}, wantErr: "无效的时间"},
+		{name: "缺少结束时间", windows: []MaintenanceWindow{{Start: "22:00"}}, wantErr: "无效的时间"},
+		{name: "无效的星期", windows: []MaintenanceWindow{{Days: []string{"someday"}, Start: "22:00", End: "06:00"}}, wantErr: "无效的星期"},
+		{
+			name:    "第二个窗口无效",
+			windows: []MaintenanceWindow{{Start: "22:00", End: "06:00"}, {Start: "x", End: "y"}},
+			wantErr: "维护窗口 2 无效",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateMaintenanceWindows(tt.windows)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("err = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestInMaintenanceWindow(t *testing.T) {
+	now := time.Date(2024, 5, 3, 12, 0, 0, 0, time.UTC)
+	if !InMaintenanceWindow(nil, now) {
+		t.Fatal("未配置维护窗口时应随时可安装")
+	}
+	windows := []MaintenanceWindow{{Start: "22:00", End: "06:00"}, {Start: "11:00", End: "13:00"}}
+	if !InMaintenanceWindow(windows, now) {
+		t.Fatal("处于任一窗口内时应返回 true")
+	}
+	if InMaintenanceWindow(windows[:1], now) {
+		t.Fatal("不在窗口内时应返回 false")
+	}
+}
+
+func TestLoadConfigRejectsInvalidMaintenanceWindow(t *testing.T) {
+	dir := t.TempDir()
+	oldDir := programDir
+	programDir = func() string { return dir }
+	t.Cleanup(func() { programDir = oldDir })
+
+	data := `{"maintenance_windows": [{"start": "22:00", "end": "6pm"}]}`
+	if err := os.WriteFile(filepath.Join(dir, "config.json"), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(); err == nil || !strings.Contains(err.Error(), "维护窗口 1 无效") {
+		t.Fatalf("LoadConfig err = %v", err)
+	}
+}